@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"zuccacm-server/handler"
+)
+
+// clubRatingCmd represents the club-rating command
+var clubRatingCmd = &cobra.Command{
+	Use:   "club-rating",
+	Short: "Recompute club rating from scratch",
+	Long: `Delete all club ratings and rate every ended contest again in order of start time.
+It is needed after standings of old contests change.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+		defer cancel()
+		n, ok := handler.RecomputeClubRating(ctx)
+		if !ok {
+			log.Fatal("Club rating is being written by the server, try again later")
+		}
+		log.WithField("contests", n).Info("Recompute club rating succeed!")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(clubRatingCmd)
+}
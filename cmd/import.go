@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"zuccacm-server/importer"
+)
+
+var importOpt importer.Options
+var importFormat, importStartTime string
+
+// importCmd represents the import command
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import contest from offline data",
+	Long: `Import contest (with problems) from offline data, supported formats:
+  codeforces: response of codeforces api contest.standings
+  atcoder:    json of atcoder standings, --start and --duration are required
+  domjudge:   zip of CLICS contest package, --oj is required
+A contest which has been imported (same oj and cid) is rejected.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		if importStartTime != "" {
+			importOpt.StartTime, err = time.ParseInLocation("2006-01-02 15:04:05", importStartTime, time.Local)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		contest, inserted, err := importer.Import(ctx, importFormat, data, importOpt)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.WithFields(log.Fields{
+			"contest_id":  contest.Id,
+			"problems":    len(contest.Problems),
+			"submissions": inserted,
+		}).Info("Import contest succeed!")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(importCmd)
+
+	importCmd.Flags().StringVarP(&importFormat, "format", "f", importer.FormatCodeforces, "format of data: codeforces | atcoder | domjudge")
+	importCmd.Flags().StringVar(&importOpt.OJ, "oj", "", "oj name of problems and submissions")
+	importCmd.Flags().StringVar(&importOpt.Name, "name", "", "contest name")
+	importCmd.Flags().StringVar(&importStartTime, "start", "", "contest start time, format like '2006-01-02 15:04:05'")
+	importCmd.Flags().IntVar(&importOpt.Duration, "duration", 0, "contest duration in minutes")
+	importCmd.Flags().IntSliceVar(&importOpt.Groups, "group", nil, "contest groups of contest")
+	importCmd.Flags().IntSliceVar(&importOpt.Teams, "team", nil, "teams participating in contest")
+	importCmd.Flags().BoolVar(&importOpt.WithSubmissions, "submissions", false, "import submissions embedded in data")
+}
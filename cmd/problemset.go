@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"zuccacm-server/importer"
+)
+
+// problemsetCmd represents the problemset command
+var problemsetCmd = &cobra.Command{
+	Use:   "problemset [file]",
+	Short: "Import problem metadata from codeforces problemset",
+	Long: `Upsert title, rating and tags of problems from offline dump of codeforces api problemset.problems
+(https://codeforces.com/api/problemset.problems)`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		data, err := os.ReadFile(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		n, err := importer.ImportProblemset(ctx, data)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.WithField("problems", n).Info("Import problemset succeed!")
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(problemsetCmd)
+}
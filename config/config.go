@@ -9,6 +9,8 @@ import (
 type Config struct {
 	LogConfig
 	ServerConfig
+	TrainingConfig
+	NotifyConfig
 	Secret
 }
 
@@ -21,11 +23,31 @@ type ServerConfig struct {
 	Port int
 }
 
+type TrainingConfig struct {
+	AttendanceThreshold float64
+	CheckInGrace        int
+	InactiveDays        int
+	DropRatio           float64
+	MinWeeklySolved     float64
+}
+
+type NotifyConfig struct {
+	ReminderMinutes int
+	ResultDelay     int
+	WebhookURL      string
+	BotType         string
+	BotURL          string
+	BotGroupId      int64
+}
+
 type Secret struct {
-	SessionKey string
-	SSO_URL    string
+	SessionKey  string
+	SSO_URL     string
+	SpiderKey   string
+	CalendarKey string
 	DBConfig
 	OSS
+	SMTP
 	MessageQueue string
 }
 
@@ -36,6 +58,14 @@ type DBConfig struct {
 	User     string
 	Pwd      string
 }
+type SMTP struct {
+	Host string
+	Port int
+	User string
+	Pwd  string
+	From string
+}
+
 type OSS struct {
 	Id     string
 	Key    string
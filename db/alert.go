@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// kinds of member alert
+const (
+	AlertInactive = "inactive" // no activity for InactiveDays days
+	AlertDrop     = "drop"     // solved of the last week drops sharply against the baseline
+)
+
+// alertBaselineWeeks is the number of weeks before the last week to calculate the baseline of weekly solved
+const alertBaselineWeeks = 4
+
+// AlertThreshold flag members who are inactive for InactiveDays days,
+// or whose solved of the last week < baseline * (1 - DropRatio) while baseline >= MinWeeklySolved
+type AlertThreshold struct {
+	InactiveDays    int     `json:"inactive_days"`
+	DropRatio       float64 `json:"drop_ratio"`
+	MinWeeklySolved float64 `json:"min_weekly_solved"`
+}
+
+// NewAlertThreshold use default values (14 days, 0.5, 3) instead of non-positive arguments
+func NewAlertThreshold(inactiveDays int, dropRatio, minWeeklySolved float64) AlertThreshold {
+	th := AlertThreshold{14, 0.5, 3}
+	if inactiveDays > 0 {
+		th.InactiveDays = inactiveDays
+	}
+	if dropRatio > 0 {
+		th.DropRatio = dropRatio
+	}
+	if minWeeklySolved > 0 {
+		th.MinWeeklySolved = minWeeklySolved
+	}
+	return th
+}
+
+// AtRiskMember LastActive is the latest of submission, check-in and rated contest, nil if never active
+type AtRiskMember struct {
+	Username       string    `json:"username"`
+	Nickname       string    `json:"nickname"`
+	LastActive     *Datetime `json:"last_active"`
+	InactiveDays   int       `json:"inactive_days"`
+	WeekSolved     int       `json:"week_solved"`
+	BaselineSolved float64   `json:"baseline_solved"`
+	Kinds          []string  `json:"kinds"`
+}
+
+type memberTrace struct {
+	Username       string       `db:"username"`
+	Nickname       string       `db:"nickname"`
+	LastSubmission sql.NullTime `db:"last_submission"`
+	LastCheckIn    sql.NullTime `db:"last_check_in"`
+	LastRating     sql.NullTime `db:"last_rating"`
+	WeekSolved     int          `db:"week_solved"`
+	PrevSolved     int          `db:"prev_solved"`
+}
+
+// DetectAtRiskMembers return enabled official users flagged by th at now
+// submissions of unverified accounts are excluded as in the leaderboard
+func DetectAtRiskMembers(ctx context.Context, th AlertThreshold, now time.Time) []AtRiskMember {
+	weekBegin := now.AddDate(0, 0, -7)
+	prevBegin := weekBegin.AddDate(0, 0, -7*alertBaselineWeeks)
+	query := `
+SELECT username, nickname,
+(
+    SELECT MAX(create_time) FROM submission
+    WHERE username = u.username AND ` + verifiedSubmissionSQL + `
+) last_submission,
+(
+    SELECT MAX(start_time) FROM contest_attendance, contest
+    WHERE contest.id = contest_attendance.contest_id AND username = u.username AND status = ?
+) last_check_in,
+(SELECT MAX(contest_time) FROM rating WHERE username = u.username) last_rating,
+(
+    SELECT COUNT(*) FROM
+    (
+         SELECT MIN(create_time) FROM submission
+         WHERE is_accepted AND username = u.username
+         AND ` + verifiedSubmissionSQL + `
+         GROUP BY oj_id, pid HAVING MIN(create_time) BETWEEN ? AND ?
+    ) tmp
+) week_solved,
+(
+    SELECT COUNT(*) FROM
+    (
+         SELECT MIN(create_time) FROM submission
+         WHERE is_accepted AND username = u.username
+         AND ` + verifiedSubmissionSQL + `
+         GROUP BY oj_id, pid HAVING MIN(create_time) BETWEEN ? AND ?
+    ) tmp
+) prev_solved
+FROM (SELECT DISTINCT username, nickname FROM official_user WHERE is_enable) u
+ORDER BY username`
+	data := make([]memberTrace, 0)
+	mustSelect(ctx, &data, query, AttendanceCheckedIn, weekBegin, now, prevBegin, weekBegin)
+
+	ret := make([]AtRiskMember, 0)
+	for _, x := range data {
+		m := AtRiskMember{
+			Username:       x.Username,
+			Nickname:       x.Nickname,
+			WeekSolved:     x.WeekSolved,
+			BaselineSolved: float64(x.PrevSolved) / alertBaselineWeeks,
+			Kinds:          make([]string, 0),
+		}
+		var last time.Time
+		for _, t := range []sql.NullTime{x.LastSubmission, x.LastCheckIn, x.LastRating} {
+			if t.Valid && t.Time.After(last) {
+				last = t.Time
+			}
+		}
+		if !last.IsZero() {
+			t := Datetime(last)
+			m.LastActive = &t
+			m.InactiveDays = int(now.Sub(last).Hours()) / 24
+		}
+		if last.IsZero() || m.InactiveDays >= th.InactiveDays {
+			m.Kinds = append(m.Kinds, AlertInactive)
+		}
+		if m.BaselineSolved >= th.MinWeeklySolved && float64(m.WeekSolved) < m.BaselineSolved*(1-th.DropRatio) {
+			m.Kinds = append(m.Kinds, AlertDrop)
+		}
+		if len(m.Kinds) > 0 {
+			ret = append(ret, m)
+		}
+	}
+	return ret
+}
+
+type MemberAlert struct {
+	Id         int       `json:"id"`
+	Username   string    `json:"username"`
+	Nickname   string    `json:"nickname"`
+	Kind       string    `json:"kind"`
+	Detail     string    `json:"detail"`
+	CreateTime Datetime  `json:"create_time"`
+	IsAcked    bool      `json:"is_acked"`
+	AckNote    string    `json:"ack_note"`
+	AckTime    *Datetime `json:"ack_time"`
+}
+
+type dbMemberAlert struct {
+	Id         int          `db:"id"`
+	Username   string       `db:"username"`
+	Nickname   string       `db:"nickname"`
+	Kind       string       `db:"kind"`
+	Detail     string       `db:"detail"`
+	CreateTime time.Time    `db:"create_time"`
+	IsAcked    bool         `db:"is_acked"`
+	AckNote    string       `db:"ack_note"`
+	AckTime    sql.NullTime `db:"ack_time"`
+}
+
+func (a *dbMemberAlert) jsonType() MemberAlert {
+	ret := MemberAlert{
+		Id:         a.Id,
+		Username:   a.Username,
+		Nickname:   a.Nickname,
+		Kind:       a.Kind,
+		Detail:     a.Detail,
+		CreateTime: Datetime(a.CreateTime),
+		IsAcked:    a.IsAcked,
+		AckNote:    a.AckNote,
+	}
+	if a.AckTime.Valid {
+		t := Datetime(a.AckTime.Time)
+		ret.AckTime = &t
+	}
+	return ret
+}
+
+// AddMemberAlert add alert unless there is an alert of the same kind (acknowledged or not) created since since
+// return whether it is added
+func AddMemberAlert(ctx context.Context, username, kind, detail string, since time.Time) bool {
+	query := `INSERT INTO member_alert(username, kind, detail, create_time)
+SELECT ?, ?, ?, ? FROM DUAL
+WHERE NOT EXISTS (SELECT * FROM member_alert WHERE username = ? AND kind = ? AND create_time >= ?)`
+	ret := instance.MustExecContext(ctx, query, username, kind, detail, time.Now(), username, kind, since)
+	cnt, err := ret.RowsAffected()
+	if err != nil {
+		panic(err)
+	}
+	return cnt > 0
+}
+
+// GetMemberAlerts return alerts, newest first, only unacknowledged ones if pendingOnly
+func GetMemberAlerts(ctx context.Context, pendingOnly bool, page Page) []MemberAlert {
+	query := `SELECT member_alert.*, nickname FROM member_alert, user
+WHERE member_alert.username = user.username`
+	if pendingOnly {
+		query += " AND NOT is_acked"
+	}
+	query += " ORDER BY create_time DESC, id DESC"
+	data := make([]dbMemberAlert, 0)
+	mustSelect(ctx, &data, page.query(query))
+	ret := make([]MemberAlert, 0)
+	for _, x := range data {
+		ret = append(ret, x.jsonType())
+	}
+	return ret
+}
+
+func AckMemberAlert(ctx context.Context, id int, note string) {
+	query := "UPDATE member_alert SET is_acked = TRUE, ack_note = ?, ack_time = ? WHERE id = ?"
+	mustExec(ctx, query, note, time.Now(), id)
+}
@@ -0,0 +1,93 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// status of contest attendance
+const (
+	AttendancePresent   = "present"    // any submission to contest problems during contest
+	AttendanceCheckedIn = "checked_in" // manual check-in
+	AttendanceExcused   = "excused"    // excused absence
+	AttendanceAbsent    = "absent"
+)
+
+// AttendanceRecord is a manual record of check-in or excused absence
+type AttendanceRecord struct {
+	ContestId int    `json:"contest_id" db:"contest_id"`
+	Username  string `json:"username" db:"username"`
+	Status    string `json:"status" db:"status"`
+	Note      string `json:"note" db:"note"`
+}
+
+type Attendance struct {
+	ContestId   int      `json:"contest_id" db:"contest_id"`
+	ContestName string   `json:"contest_name" db:"contest_name"`
+	StartTime   Datetime `json:"start_time" db:"start_time"`
+	Username    string   `json:"username" db:"username"`
+	Nickname    string   `json:"nickname" db:"nickname"`
+	Submitted   bool     `json:"-" db:"submitted"`
+	Record      string   `json:"-" db:"record"`
+	Note        string   `json:"note" db:"note"`
+	Status      string   `json:"status"`
+}
+
+// UpdAttendanceRecord update if record already exists, otherwise insert
+func UpdAttendanceRecord(ctx context.Context, record AttendanceRecord) {
+	query := `INSERT INTO contest_attendance(contest_id, username, status, note)
+VALUES(:contest_id, :username, :status, :note)
+ON DUPLICATE KEY UPDATE status=VALUES(status), note=VALUES(note)`
+	mustNamedExec(ctx, query, record)
+}
+
+func DelAttendanceRecord(ctx context.Context, contestId int, username string) {
+	mustExec(ctx, "DELETE FROM contest_attendance WHERE contest_id=? AND username=?", contestId, username)
+}
+
+// GetAttendances return attendance of users in contests started during [begin, end] and before now
+// users are those who should participant in via contest_team_rel
+// return attendance of any users if username is empty, and of any contest groups if groupId <= 0
+func GetAttendances(ctx context.Context, username string, groupId int, begin, end time.Time) []Attendance {
+	query := `
+SELECT DISTINCT contest.id AS contest_id, contest.name AS contest_name, contest.start_time AS start_time,
+user.username AS username, nickname,
+EXISTS
+(
+    SELECT * FROM submission, contest_problem
+    WHERE submission.oj_id = contest_problem.oj_id AND submission.pid = contest_problem.pid
+      AND contest_problem.contest_id = contest.id AND submission.username = user.username
+      AND ` + verifiedSubmissionSQL + `
+      AND create_time BETWEEN contest.start_time AND DATE_ADD(contest.start_time, INTERVAL contest.duration MINUTE)
+) submitted,
+IFNULL(contest_attendance.status, '') AS record,
+IFNULL(contest_attendance.note, '') AS note
+FROM contest
+JOIN contest_team_rel ON contest_team_rel.contest_id = contest.id
+JOIN team_user_rel ON team_user_rel.team_id = contest_team_rel.team_id
+JOIN user ON user.username = team_user_rel.username
+LEFT JOIN contest_attendance ON contest_attendance.contest_id = contest.id AND contest_attendance.username = user.username
+WHERE contest.start_time BETWEEN ? AND ? AND contest.start_time < NOW()`
+	args := []interface{}{begin, end}
+	if username != "" {
+		query += " AND user.username = ?"
+		args = append(args, username)
+	}
+	if groupId > 0 {
+		query += fmt.Sprintf(" AND contest.id IN (SELECT contest_id FROM contest_group_rel WHERE group_id = %d)", groupId)
+	}
+	query += " ORDER BY start_time DESC, username"
+	ret := make([]Attendance, 0)
+	mustSelect(ctx, &ret, query, args...)
+	for i, x := range ret {
+		if x.Submitted {
+			ret[i].Status = AttendancePresent
+		} else if x.Record != "" {
+			ret[i].Status = x.Record
+		} else {
+			ret[i].Status = AttendanceAbsent
+		}
+	}
+	return ret
+}
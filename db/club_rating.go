@@ -0,0 +1,178 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ClubOjId is the reserved oj id of club rating in rating, it is never an enabled oj
+const ClubOjId = 0
+
+// ClubInitialRating is the rating of users before their first rated contest
+const ClubInitialRating = 1500
+
+// GetClubRatings return the current club rating of rated users
+func GetClubRatings(ctx context.Context) map[string]int {
+	query := `
+SELECT username, rating FROM
+(
+    SELECT username, rating, ROW_NUMBER() OVER (PARTITION BY username ORDER BY contest_time DESC) AS rn
+    FROM rating WHERE oj_id = ?
+) ranked
+WHERE rn = 1`
+	var data []struct {
+		Username string `db:"username"`
+		Rating   int    `db:"rating"`
+	}
+	mustSelect(ctx, &data, query, ClubOjId)
+	ret := make(map[string]int)
+	for _, x := range data {
+		ret[x.Username] = x.Rating
+	}
+	return ret
+}
+
+// lastRatedStartSQL select start time of the last rated contest, NULL if none is rated
+const lastRatedStartSQL = `SELECT MAX(start_time) FROM contest WHERE id IN (SELECT contest_id FROM club_rating_contest)`
+
+// GetUnratedContests return contests (without problems) ended before end which start after the last rated contest
+// contests earlier than the last rated one are only rated by recomputing, see HasEarlierUnratedContest
+func GetUnratedContests(ctx context.Context, end time.Time) []Contest {
+	query := `
+SELECT * FROM contest
+WHERE DATE_ADD(start_time, INTERVAL duration MINUTE) < ?
+AND start_time > IFNULL((` + lastRatedStartSQL + `), '1000-01-01')
+ORDER BY start_time, id`
+	ret := make([]Contest, 0)
+	mustSelect(ctx, &ret, query, end)
+	return ret
+}
+
+// HasEarlierUnratedContest return whether a contest ended before end is not rated but starts before the last rated one
+// such as a contest added or imported late, ratings have to be recomputed to rate it in order
+func HasEarlierUnratedContest(ctx context.Context, end time.Time) bool {
+	query := `
+SELECT EXISTS(
+    SELECT * FROM contest
+    WHERE DATE_ADD(start_time, INTERVAL duration MINUTE) < ?
+    AND id NOT IN (SELECT contest_id FROM club_rating_contest)
+    AND start_time <= (` + lastRatedStartSQL + `)
+)`
+	var ret bool
+	mustGet(ctx, &ret, query, end)
+	return ret
+}
+
+// clubRatingLock is the name of the db lock held while club ratings are written
+const clubRatingLock = "club_rating"
+
+// LockClubRating acquire a db lock so that club ratings are written by one process at a time
+// it waits at most wait, ok=false if the lock is held by others
+func LockClubRating(ctx context.Context, wait time.Duration) (unlock func(), ok bool) {
+	// the lock belongs to the session, so a dedicated connection is held until unlock
+	conn, err := instance.Connx(ctx)
+	if err != nil {
+		panic(err)
+	}
+	var got sql.NullInt64
+	if err := conn.GetContext(ctx, &got, "SELECT GET_LOCK(?, ?)", clubRatingLock, int(wait.Seconds())); err != nil {
+		conn.Close()
+		panic(err)
+	}
+	if got.Int64 != 1 {
+		conn.Close()
+		return nil, false
+	}
+	return func() {
+		// ctx may be done, the lock is still released
+		c, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if _, err := conn.ExecContext(c, "SELECT RELEASE_LOCK(?)", clubRatingLock); err != nil {
+			log.WithField("error", err).Error("release club rating lock failed")
+		}
+		conn.Close()
+	}, true
+}
+
+// AddClubRating add ratings of the contest and mark it as rated
+func AddClubRating(ctx context.Context, contestId int, ratings []Rating) {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	if len(ratings) > 0 {
+		mustNamedExecTx(tx, ctx, addRatingSQL, ratings)
+	}
+	query := "INSERT INTO club_rating_contest(contest_id, rate_time) VALUES(?, ?)"
+	mustExecTx(tx, ctx, query, contestId, time.Now())
+	mustCommit(tx)
+}
+
+// ClearClubRating delete all club ratings, contests will be rated again
+func ClearClubRating(ctx context.Context) {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	mustExecTx(tx, ctx, "DELETE FROM rating WHERE oj_id = ?", ClubOjId)
+	mustExecTx(tx, ctx, "DELETE FROM club_rating_contest")
+	mustCommit(tx)
+}
+
+// RatingChange is a rating record for charts
+type RatingChange struct {
+	Rating      int      `json:"rating" db:"rating"`
+	Delta       int      `json:"delta" db:"delta"`
+	ContestRank int      `json:"contest_rank" db:"contest_rank"`
+	ContestTime Datetime `json:"contest_time" db:"contest_time"`
+	ContestName string   `json:"contest_name" db:"contest_name"`
+	ContestURL  string   `json:"contest_url" db:"contest_url"`
+}
+
+// GetRatingHistory return rating changes of the user on the oj in time order
+func GetRatingHistory(ctx context.Context, username string, ojId int) []RatingChange {
+	query := `SELECT rating, delta, contest_rank, contest_time, contest_name, contest_url
+FROM rating WHERE username = ? AND oj_id = ? ORDER BY contest_time`
+	var data []Rating
+	mustSelect(ctx, &data, query, username, ojId)
+	ret := make([]RatingChange, 0)
+	for _, x := range data {
+		ret = append(ret, RatingChange{
+			Rating:      x.Rating,
+			Delta:       x.Delta,
+			ContestRank: x.ContestRank,
+			ContestTime: Datetime(x.ContestTime),
+			ContestName: x.ContestName,
+			ContestURL:  x.ContestURL,
+		})
+	}
+	return ret
+}
+
+type ClubRatingRow struct {
+	Username  string `json:"username" db:"username"`
+	Nickname  string `json:"nickname" db:"nickname"`
+	Rating    int    `json:"rating" db:"rating"`
+	MaxRating int    `json:"max_rating" db:"max_rating"`
+	Contests  int    `json:"contests" db:"contests"`
+}
+
+// GetClubRatingLeaderboard return rated users ordered by current club rating, only enabled users if isEnable
+func GetClubRatingLeaderboard(ctx context.Context, isEnable bool) []ClubRatingRow {
+	query := `
+SELECT user.username AS username, nickname, rating, max_rating, contests FROM
+(
+    SELECT username, rating,
+           MAX(rating) OVER (PARTITION BY username) AS max_rating,
+           COUNT(*) OVER (PARTITION BY username) AS contests,
+           ROW_NUMBER() OVER (PARTITION BY username ORDER BY contest_time DESC) AS rn
+    FROM rating WHERE oj_id = ?
+) ranked, user
+WHERE ranked.username = user.username AND rn = 1`
+	if isEnable {
+		query += " AND is_enable"
+	}
+	query += " ORDER BY rating DESC, username"
+	ret := make([]ClubRatingRow, 0)
+	mustSelect(ctx, &ret, query, ClubOjId)
+	return ret
+}
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"sort"
 	"time"
+
+	"github.com/jmoiron/sqlx"
 )
 
 type ContestGroup struct {
@@ -31,6 +33,18 @@ type ContestTeamRel struct {
 	TeamId    int `json:"team_id" db:"team_id"`
 }
 
+// scoring mode of contest
+const (
+	ScoringModeICPC = "icpc" // binary accepted, ranked by solved
+	ScoringModeOI   = "oi"   // partial score, ranked by sum of scores
+)
+
+// score rule of OI contest, which score of a problem is taken
+const (
+	ScoreRuleBest = "best"
+	ScoreRuleLast = "last"
+)
+
 type Contest struct {
 	Id           int       `json:"id" db:"id"`
 	OjId         int       `json:"oj_id" db:"oj_id"`
@@ -40,6 +54,8 @@ type Contest struct {
 	Duration     int       `json:"duration" db:"duration"`
 	MaxSolved    int       `json:"max_solved" db:"max_solved"`
 	Participants int       `json:"participants" db:"participants"`
+	ScoringMode  string    `json:"scoring_mode" db:"scoring_mode"`
+	ScoreRule    string    `json:"score_rule" db:"score_rule"`
 	Problems     []Problem `json:"problems"`
 	Groups       []int     `json:"groups"`
 	Teams        []int     `json:"teams"`
@@ -54,12 +70,22 @@ type dbContest struct {
 	Duration     int       `json:"duration" db:"duration"`
 	MaxSolved    int       `json:"max_solved" db:"max_solved"`
 	Participants int       `json:"participants" db:"participants"`
+	ScoringMode  string    `json:"scoring_mode" db:"scoring_mode"`
+	ScoreRule    string    `json:"score_rule" db:"score_rule"`
 	Problems     []Problem `json:"problems"`
 	Groups       []int     `json:"groups"`
 	Teams        []int     `json:"teams"`
 }
 
+// dbType use ICPC mode and best score rule if not specified
 func (c *Contest) dbType() *dbContest {
+	mode, rule := c.ScoringMode, c.ScoreRule
+	if mode == "" {
+		mode = ScoringModeICPC
+	}
+	if rule == "" {
+		rule = ScoreRuleBest
+	}
 	return &dbContest{
 		Id:           c.Id,
 		OjId:         c.OjId,
@@ -69,6 +95,8 @@ func (c *Contest) dbType() *dbContest {
 		Duration:     c.Duration,
 		MaxSolved:    c.MaxSolved,
 		Participants: c.Participants,
+		ScoringMode:  mode,
+		ScoreRule:    rule,
 		Problems:     c.Problems,
 		Groups:       c.Groups,
 		Teams:        c.Teams,
@@ -138,12 +166,20 @@ func GetGroupsByContest(ctx context.Context, contestId int) []ContestGroup {
 	return groups
 }
 
+// GetContestRelIds return id of groups and teams of the contest
+func GetContestRelIds(ctx context.Context, contestId int) (groups, teams []int) {
+	groups, teams = make([]int, 0), make([]int, 0)
+	mustSelect(ctx, &groups, "SELECT group_id FROM contest_group_rel WHERE contest_id = ?", contestId)
+	mustSelect(ctx, &teams, "SELECT team_id FROM contest_team_rel WHERE contest_id = ?", contestId)
+	return
+}
+
 // GetContestsByUser get contests (with problems) the user should participant in during [begin, end]
 // If groupId=0 then return contests in any groups meets the above conditions
 func GetContestsByUser(ctx context.Context, username string, begin, end time.Time, groupId int) []Contest {
 	contests := make([]Contest, 0)
 	query := `
-SELECT id, name, start_time, duration FROM contest
+SELECT id, name, start_time, duration, scoring_mode, score_rule FROM contest
 WHERE start_time BETWEEN ? AND ?
 AND id IN
 (
@@ -208,12 +244,29 @@ func GetContestById(ctx context.Context, id int) Contest {
 	return c
 }
 
+// GetContestIdByCid return id of the contest of oj with cid, ok is false if not found
+func GetContestIdByCid(ctx context.Context, ojId int, cid string) (id int, ok bool) {
+	ids := make([]int, 0)
+	mustSelect(ctx, &ids, "SELECT id FROM contest WHERE oj_id=? AND cid=? LIMIT 1", ojId, cid)
+	if len(ids) == 0 {
+		return 0, false
+	}
+	return ids[0], true
+}
+
 // AddContest return the new Contest with Contest.Id
 func AddContest(ctx context.Context, c Contest) Contest {
-	query := `INSERT INTO contest(oj_id, cid, name, start_time, duration, max_solved, participants)
-VALUES(:oj_id, :cid, :name, :start_time, :duration, :max_solved, :participants)`
 	tx := instance.MustBeginTx(ctx, nil)
 	defer tx.Rollback()
+	c = addContestTx(tx, ctx, c)
+	mustCommit(tx)
+	return c
+}
+
+// addContestTx is AddContest in tx
+func addContestTx(tx *sqlx.Tx, ctx context.Context, c Contest) Contest {
+	query := `INSERT INTO contest(oj_id, cid, name, start_time, duration, max_solved, participants, scoring_mode, score_rule)
+VALUES(:oj_id, :cid, :name, :start_time, :duration, :max_solved, :participants, :scoring_mode, :score_rule)`
 	res := mustNamedExecTx(tx, ctx, query, c.dbType())
 	id, err := res.LastInsertId()
 	if err != nil {
@@ -225,6 +278,7 @@ VALUES(:oj_id, :cid, :name, :start_time, :duration, :max_solved, :participants)`
 			c.Problems[i].ContestId = c.Id
 		}
 		mustNamedExecTx(tx, ctx, addContestProblemSQL, c.Problems)
+		addProblemsTx(tx, ctx, c.Problems)
 	}
 	if len(c.Groups) > 0 {
 		groups := make([]ContestGroupRel, 0)
@@ -246,7 +300,6 @@ VALUES(:oj_id, :cid, :name, :start_time, :duration, :max_solved, :participants)`
 		}
 		mustNamedExecTx(tx, ctx, addContestTeamRelSQL, teams)
 	}
-	mustCommit(tx)
 	return c
 }
 
@@ -255,7 +308,8 @@ func UpdContest(ctx context.Context, c Contest) {
 	defer tx.Rollback()
 	query := `UPDATE contest
 SET oj_id=:oj_id, cid=:cid, name=:name, start_time=:start_time,
-duration=:duration, max_solved=:max_solved, participants=:participants
+duration=:duration, max_solved=:max_solved, participants=:participants,
+scoring_mode=:scoring_mode, score_rule=:score_rule
 WHERE id=:id`
 	mustNamedExecTx(tx, ctx, query, c.dbType())
 	mustExecTx(tx, ctx, "DELETE FROM contest_problem WHERE contest_id=?", c.Id)
@@ -266,6 +320,7 @@ WHERE id=:id`
 			c.Problems[i].ContestId = c.Id
 		}
 		mustNamedExecTx(tx, ctx, addContestProblemSQL, c.Problems)
+		addProblemsTx(tx, ctx, c.Problems)
 	}
 	if len(c.Groups) > 0 {
 		groups := make([]ContestGroupRel, 0)
@@ -310,6 +365,7 @@ WHERE id=:id`
 			c.Problems[i].ContestId = c.Id
 		}
 		mustNamedExecTx(tx, ctx, addContestProblemSQL, c.Problems)
+		addProblemsTx(tx, ctx, c.Problems)
 	}
 	mustCommit(tx)
 }
@@ -366,14 +422,14 @@ SELECT username, nickname,
     WHERE submission.oj_id = contest_problem.oj_id AND submission.pid = contest_problem.pid
       AND contest.id = contest_problem.contest_id AND is_accepted=true
       AND create_time BETWEEN start_time AND DATE_ADD(start_time, interval duration MINUTE )
-      AND user.username = submission.username
+      AND user.username = submission.username AND ` + verifiedSubmissionSQL + `
       AND contest_id IN (SELECT id FROM contest WHERE start_time BETWEEN ? AND ?)
 ) solved,
 (
     SELECT COUNT(DISTINCT submission.pid, submission.oj_id)
     FROM submission, contest_problem
     WHERE submission.oj_id = contest_problem.oj_id AND submission.pid = contest_problem.pid
-      AND is_accepted=true AND user.username = submission.username
+      AND is_accepted=true AND user.username = submission.username AND ` + verifiedSubmissionSQL + `
       AND contest_id IN (SELECT id FROM contest WHERE start_time BETWEEN ? AND ?)
 ) upsolved
 FROM user
@@ -404,14 +460,14 @@ SELECT username, nickname,
     WHERE submission.oj_id = contest_problem.oj_id AND submission.pid = contest_problem.pid
       AND contest.id = contest_problem.contest_id AND is_accepted=true
       AND create_time BETWEEN start_time AND DATE_ADD(start_time, interval duration MINUTE )
-      AND user.username = submission.username
+      AND user.username = submission.username AND ` + verifiedSubmissionSQL + `
       AND contest_id IN (SELECT contest_id FROM contest_group_rel, contest WHERE contest_id = contest.id AND group_id = ? AND start_time BETWEEN ? AND ?)
 ) solved,
 (
     SELECT COUNT(DISTINCT submission.pid, submission.oj_id)
     FROM submission, contest_problem
     WHERE submission.oj_id = contest_problem.oj_id AND submission.pid = contest_problem.pid
-      AND is_accepted=true AND user.username = submission.username
+      AND is_accepted=true AND user.username = submission.username AND ` + verifiedSubmissionSQL + `
       AND contest_id IN (SELECT contest_id FROM contest_group_rel, contest WHERE contest_id = contest.id AND group_id = ? AND start_time BETWEEN ? AND ?)
 ) upsolved
 FROM user
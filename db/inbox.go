@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// kinds of in-app notification
+const (
+	InboxAccount = "account" // oj accounts of the user are changed
+	InboxAward   = "award"   // team of the user is added to xcpc or wins an award
+	InboxTeam    = "team"    // the user joins or leaves a team, or the team is updated
+	InboxTask    = "task"    // crawling task of the user is finished, or rating of the user is updated
+)
+
+// Notification is an in-app message to a user
+type Notification struct {
+	Id         int      `json:"id" db:"id"`
+	Username   string   `json:"username" db:"username"`
+	Kind       string   `json:"kind" db:"kind"`
+	Content    string   `json:"content" db:"content"`
+	IsRead     bool     `json:"is_read" db:"is_read"`
+	CreateTime Datetime `json:"create_time" db:"create_time"`
+}
+
+// AddNotifications send the same content to users, return the added notifications
+func AddNotifications(ctx context.Context, usernames []string, kind, content string) []Notification {
+	ret := make([]Notification, 0)
+	if len(usernames) == 0 {
+		return ret
+	}
+	now := Datetime(time.Now().Truncate(time.Second))
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	query := "INSERT INTO user_notification(username, kind, content, is_read, create_time) VALUES(?, ?, ?, false, ?)"
+	for _, username := range usernames {
+		res := tx.MustExecContext(ctx, query, username, kind, content, time.Time(now))
+		id, err := res.LastInsertId()
+		if err != nil {
+			panic(err)
+		}
+		ret = append(ret, Notification{
+			Id:         int(id),
+			Username:   username,
+			Kind:       kind,
+			Content:    content,
+			CreateTime: now,
+		})
+	}
+	mustCommit(tx)
+	return ret
+}
+
+// GetNotifications return notifications of the user, latest first
+func GetNotifications(ctx context.Context, username string, unreadOnly bool, page Page) []Notification {
+	query := "SELECT * FROM user_notification WHERE username = ?"
+	if unreadOnly {
+		query += " AND NOT is_read"
+	}
+	query += " ORDER BY id DESC"
+	ret := make([]Notification, 0)
+	mustSelect(ctx, &ret, page.query(query), username)
+	return ret
+}
+
+func GetUnreadCount(ctx context.Context, username string) (ret int) {
+	mustGet(ctx, &ret, "SELECT COUNT(*) FROM user_notification WHERE username = ? AND NOT is_read", username)
+	return
+}
+
+func ReadNotification(ctx context.Context, username string, id int) {
+	mustExec(ctx, "UPDATE user_notification SET is_read = true WHERE username = ? AND id = ?", username, id)
+}
+
+func ReadAllNotifications(ctx context.Context, username string) {
+	mustExec(ctx, "UPDATE user_notification SET is_read = true WHERE username = ? AND NOT is_read", username)
+}
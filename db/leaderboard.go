@@ -0,0 +1,321 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+
+	"zuccacm-server/enum/errorx"
+)
+
+// kinds of club-wide leaderboard
+// seasons are [Mar 1, Sep 1) (spring) and [Sep 1, Mar 1) (autumn)
+const (
+	LeaderboardWeek   = "week"
+	LeaderboardMonth  = "month"
+	LeaderboardSeason = "season"
+)
+
+// categories of club-wide leaderboard
+const (
+	CategorySolved   = "solved"   // problems newly solved in the period
+	CategoryActive   = "active"   // days with submissions in the period
+	CategoryImproved = "improved" // solved minus solved of the previous period
+)
+
+var LeaderboardKinds = []string{LeaderboardWeek, LeaderboardMonth, LeaderboardSeason}
+
+var leaderboardCategories = []string{CategorySolved, CategoryActive, CategoryImproved}
+
+// LeaderboardPeriod return name and [begin, end] of the period of kind which t is in
+func LeaderboardPeriod(kind string, t time.Time) (name string, begin, end time.Time) {
+	y, m, d := t.Date()
+	var next time.Time
+	switch kind {
+	case LeaderboardWeek:
+		begin = time.Date(y, m, d-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+		next = begin.AddDate(0, 0, 7)
+		year, week := begin.ISOWeek()
+		name = fmt.Sprintf("%d-W%02d", year, week)
+	case LeaderboardMonth:
+		begin = time.Date(y, m, 1, 0, 0, 0, 0, t.Location())
+		next = begin.AddDate(0, 1, 0)
+		name = begin.Format("2006-01")
+	case LeaderboardSeason:
+		switch {
+		case m < time.March:
+			begin = time.Date(y-1, time.September, 1, 0, 0, 0, 0, t.Location())
+			name = fmt.Sprintf("%d Autumn", y-1)
+		case m < time.September:
+			begin = time.Date(y, time.March, 1, 0, 0, 0, 0, t.Location())
+			name = fmt.Sprintf("%d Spring", y)
+		default:
+			begin = time.Date(y, time.September, 1, 0, 0, 0, 0, t.Location())
+			name = fmt.Sprintf("%d Autumn", y)
+		}
+		next = begin.AddDate(0, 6, 0)
+	default:
+		panic(errorx.ErrBadRequest.WithMessage("unknown leaderboard kind: " + kind))
+	}
+	return name, begin, next.Add(-time.Second)
+}
+
+// LeaderboardEntry RankChange = PrevRank - Rank, it is 0 if the user is not ranked in the previous period
+type LeaderboardEntry struct {
+	Category   string `json:"-" db:"category"`
+	Username   string `json:"username" db:"username"`
+	Nickname   string `json:"nickname" db:"nickname"`
+	Rank       int    `json:"rank" db:"ranking"`
+	Value      int    `json:"value" db:"value"`
+	PrevRank   int    `json:"prev_rank" db:"prev_ranking"`
+	RankChange int    `json:"rank_change" db:"-"`
+}
+
+type Leaderboard struct {
+	Id         int                           `json:"id"`
+	Kind       string                        `json:"kind"`
+	Name       string                        `json:"name"`
+	BeginTime  Datetime                      `json:"begin_time"`
+	EndTime    Datetime                      `json:"end_time"`
+	IsFinal    bool                          `json:"is_final"`
+	Categories map[string][]LeaderboardEntry `json:"categories,omitempty"`
+}
+
+type dbLeaderboard struct {
+	Id         int       `db:"id"`
+	Kind       string    `db:"kind"`
+	Name       string    `db:"name"`
+	BeginTime  time.Time `db:"begin_time"`
+	EndTime    time.Time `db:"end_time"`
+	CreateTime time.Time `db:"create_time"`
+}
+
+func (l *dbLeaderboard) jsonType() *Leaderboard {
+	return &Leaderboard{
+		Id:        l.Id,
+		Kind:      l.Kind,
+		Name:      l.Name,
+		BeginTime: Datetime(l.BeginTime),
+		EndTime:   Datetime(l.EndTime),
+		IsFinal:   true,
+	}
+}
+
+type memberActivity struct {
+	Username    string `db:"username"`
+	Nickname    string `db:"nickname"`
+	Solved      int    `db:"solved"`
+	Submissions int    `db:"submissions"`
+	ActiveDays  int    `db:"active_days"`
+}
+
+// getMemberActivities return activities of enabled official users during [begin, end]
+// submissions of unverified accounts are excluded, see GetOverview
+func getMemberActivities(ctx context.Context, begin, end time.Time) []memberActivity {
+	query := `
+SELECT username, nickname,
+(
+    SELECT COUNT(*) FROM
+    (
+         SELECT MIN(create_time) FROM submission
+         WHERE is_accepted AND username = u.username
+         AND ` + verifiedSubmissionSQL + `
+         GROUP BY oj_id, pid HAVING MIN(create_time) BETWEEN ? AND ?
+    ) tmp
+) solved,
+(
+    SELECT COUNT(*) FROM submission
+    WHERE username = u.username AND create_time BETWEEN ? AND ?
+    AND ` + verifiedSubmissionSQL + `
+) submissions,
+(
+    SELECT COUNT(DISTINCT DATE(create_time)) FROM submission
+    WHERE username = u.username AND create_time BETWEEN ? AND ?
+    AND ` + verifiedSubmissionSQL + `
+) active_days
+FROM (SELECT DISTINCT username, nickname FROM official_user WHERE is_enable) u`
+	ret := make([]memberActivity, 0)
+	mustSelect(ctx, &ret, query, begin, end, begin, end, begin, end)
+	return ret
+}
+
+// rankLeaderboard rank users in each category, users with the same value have the same rank
+func rankLeaderboard(cur, prev []memberActivity) map[string][]LeaderboardEntry {
+	prevSolved := make(map[string]int)
+	for _, x := range prev {
+		prevSolved[x.Username] = x.Solved
+	}
+	ret := make(map[string][]LeaderboardEntry)
+	for _, c := range leaderboardCategories {
+		entries := make([]LeaderboardEntry, 0)
+		for _, x := range cur {
+			e := LeaderboardEntry{Category: c, Username: x.Username, Nickname: x.Nickname}
+			switch c {
+			case CategorySolved:
+				e.Value = x.Solved
+			case CategoryActive:
+				e.Value = x.ActiveDays
+			case CategoryImproved:
+				e.Value = x.Solved - prevSolved[x.Username]
+			}
+			entries = append(entries, e)
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			if entries[i].Value != entries[j].Value {
+				return entries[i].Value > entries[j].Value
+			}
+			return entries[i].Username < entries[j].Username
+		})
+		for i := range entries {
+			entries[i].Rank = i + 1
+			if i > 0 && entries[i].Value == entries[i-1].Value {
+				entries[i].Rank = entries[i-1].Rank
+			}
+		}
+		ret[c] = entries
+	}
+	return ret
+}
+
+// CalcLeaderboard calculate the leaderboard of kind of the period which t is in
+func CalcLeaderboard(ctx context.Context, kind string, t time.Time) Leaderboard {
+	name, begin, end := LeaderboardPeriod(kind, t)
+	_, prevBegin, prevEnd := LeaderboardPeriod(kind, begin.Add(-time.Second))
+	_, prev2Begin, prev2End := LeaderboardPeriod(kind, prevBegin.Add(-time.Second))
+	cur := getMemberActivities(ctx, begin, end)
+	prev := getMemberActivities(ctx, prevBegin, prevEnd)
+	prev2 := getMemberActivities(ctx, prev2Begin, prev2End)
+
+	prevRanks := make(map[string]map[string]int)
+	for c, entries := range rankLeaderboard(prev, prev2) {
+		prevRanks[c] = make(map[string]int)
+		for _, e := range entries {
+			prevRanks[c][e.Username] = e.Rank
+		}
+	}
+	categories := rankLeaderboard(cur, prev)
+	for c, entries := range categories {
+		for i, e := range entries {
+			entries[i].PrevRank = prevRanks[c][e.Username]
+			if entries[i].PrevRank > 0 {
+				entries[i].RankChange = entries[i].PrevRank - e.Rank
+			}
+		}
+	}
+	return Leaderboard{
+		Kind:       kind,
+		Name:       name,
+		BeginTime:  Datetime(begin),
+		EndTime:    Datetime(end),
+		IsFinal:    false,
+		Categories: categories,
+	}
+}
+
+// SaveLeaderboard persist the leaderboard as final, the previous one of the same period is replaced
+func SaveLeaderboard(ctx context.Context, l Leaderboard) int {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	mustExecTx(tx, ctx, "DELETE FROM leaderboard WHERE kind=? AND begin_time=?", l.Kind, time.Time(l.BeginTime))
+	query := "INSERT INTO leaderboard(kind, name, begin_time, end_time, create_time) VALUES(?, ?, ?, ?, ?)"
+	ret := tx.MustExecContext(ctx, query, l.Kind, l.Name, time.Time(l.BeginTime), time.Time(l.EndTime), time.Now())
+	id, err := ret.LastInsertId()
+	if err != nil {
+		panic(err)
+	}
+	type dbEntry struct {
+		LeaderboardId int64  `db:"leaderboard_id"`
+		Category      string `db:"category"`
+		Username      string `db:"username"`
+		Rank          int    `db:"ranking"`
+		Value         int    `db:"value"`
+		PrevRank      int    `db:"prev_ranking"`
+	}
+	data := make([]dbEntry, 0)
+	for c, entries := range l.Categories {
+		for _, e := range entries {
+			data = append(data, dbEntry{id, c, e.Username, e.Rank, e.Value, e.PrevRank})
+		}
+	}
+	if len(data) > 0 {
+		query = `INSERT INTO leaderboard_entry(leaderboard_id, category, username, ranking, value, prev_ranking)
+VALUES(:leaderboard_id, :category, :username, :ranking, :value, :prev_ranking)`
+		mustNamedExecTx(tx, ctx, query, data)
+	}
+	mustCommit(tx)
+	return int(id)
+}
+
+// GetLeaderboards return final leaderboards (without entries) of kind, all kinds if kind is empty
+func GetLeaderboards(ctx context.Context, kind string) []Leaderboard {
+	query := "SELECT * FROM leaderboard"
+	args := make([]interface{}, 0)
+	if kind != "" {
+		query += " WHERE kind=?"
+		args = append(args, kind)
+	}
+	query += " ORDER BY begin_time DESC, kind"
+	data := make([]dbLeaderboard, 0)
+	mustSelect(ctx, &data, query, args...)
+	ret := make([]Leaderboard, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+func loadLeaderboardEntries(ctx context.Context, l *Leaderboard) {
+	query := `SELECT category, leaderboard_entry.username AS username, nickname, ranking, value, prev_ranking
+FROM leaderboard_entry, user
+WHERE leaderboard_entry.username = user.username AND leaderboard_id = ?
+ORDER BY category, ranking, username`
+	data := make([]LeaderboardEntry, 0)
+	mustSelect(ctx, &data, query, l.Id)
+	l.Categories = make(map[string][]LeaderboardEntry)
+	for _, c := range leaderboardCategories {
+		l.Categories[c] = make([]LeaderboardEntry, 0)
+	}
+	for _, e := range data {
+		if e.PrevRank > 0 {
+			e.RankChange = e.PrevRank - e.Rank
+		}
+		l.Categories[e.Category] = append(l.Categories[e.Category], e)
+	}
+}
+
+// GetLeaderboardById return the final leaderboard with entries
+func GetLeaderboardById(ctx context.Context, id int) Leaderboard {
+	var data dbLeaderboard
+	err := instance.GetContext(ctx, &data, "SELECT * FROM leaderboard WHERE id=?", id)
+	if err == sql.ErrNoRows {
+		panic(errorx.ErrNotFound.New())
+	}
+	if err != nil {
+		panic(err)
+	}
+	l := data.jsonType()
+	loadLeaderboardEntries(ctx, l)
+	return *l
+}
+
+// GetFinalLeaderboard return the final leaderboard of kind beginning at begin, ok is false if not finalized
+func GetFinalLeaderboard(ctx context.Context, kind string, begin time.Time) (l Leaderboard, ok bool) {
+	var id int
+	err := instance.GetContext(ctx, &id, "SELECT id FROM leaderboard WHERE kind=? AND begin_time=?", kind, begin)
+	if err == sql.ErrNoRows {
+		return l, false
+	}
+	if err != nil {
+		panic(err)
+	}
+	return GetLeaderboardById(ctx, id), true
+}
+
+// GetLastFinalLeaderboardBegin return begin time of the latest final leaderboard of the kind
+func GetLastFinalLeaderboardBegin(ctx context.Context, kind string) (begin time.Time, ok bool) {
+	var t sql.NullTime
+	mustGet(ctx, &t, "SELECT MAX(begin_time) FROM leaderboard WHERE kind=?", kind)
+	return t.Time, t.Valid
+}
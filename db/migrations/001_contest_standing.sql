@@ -0,0 +1,8 @@
+-- standings snapshot of finished contests
+CREATE TABLE IF NOT EXISTS contest_standing
+(
+    contest_id  INT         NOT NULL PRIMARY KEY,
+    data        MEDIUMTEXT  NOT NULL,
+    create_time DATETIME    NOT NULL,
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE
+);
@@ -0,0 +1,10 @@
+-- scoring config of series leaderboard of a contest group
+CREATE TABLE IF NOT EXISTS contest_group_scoring
+(
+    group_id       INT          NOT NULL PRIMARY KEY,
+    rule           VARCHAR(32)  NOT NULL DEFAULT 'solved',
+    rank_points    TEXT         NOT NULL,
+    drop_worst     INT          NOT NULL DEFAULT 0,
+    upsolve_weight DOUBLE       NOT NULL DEFAULT 0,
+    FOREIGN KEY (group_id) REFERENCES contest_group (id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- manual check-in and excused absence of contests
+CREATE TABLE IF NOT EXISTS contest_attendance
+(
+    contest_id INT          NOT NULL,
+    username   VARCHAR(64)  NOT NULL,
+    status     VARCHAR(16)  NOT NULL,
+    note       VARCHAR(255) NOT NULL DEFAULT '',
+    PRIMARY KEY (contest_id, username),
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE,
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
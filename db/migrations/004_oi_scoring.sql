@@ -0,0 +1,7 @@
+-- scoring mode of contest and partial score of submission
+ALTER TABLE contest
+    ADD COLUMN scoring_mode VARCHAR(16) NOT NULL DEFAULT 'icpc',
+    ADD COLUMN score_rule   VARCHAR(16) NOT NULL DEFAULT 'best';
+
+ALTER TABLE submission
+    ADD COLUMN score DOUBLE NULL DEFAULT NULL;
@@ -0,0 +1,10 @@
+-- virtual participation of finished contests
+CREATE TABLE IF NOT EXISTS contest_virtual
+(
+    contest_id INT         NOT NULL,
+    username   VARCHAR(64) NOT NULL,
+    start_time DATETIME    NOT NULL,
+    PRIMARY KEY (contest_id, username),
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE,
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+-- default settings of new contests
+CREATE TABLE IF NOT EXISTS contest_template
+(
+    id           INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    oj_id        INT          NOT NULL DEFAULT 0,
+    duration     INT          NOT NULL DEFAULT 0,
+    scoring_mode VARCHAR(16)  NOT NULL DEFAULT '',
+    group_ids    TEXT         NOT NULL,
+    team_ids     TEXT         NOT NULL
+);
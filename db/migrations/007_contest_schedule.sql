@@ -0,0 +1,26 @@
+-- recurring schedules which create contests ahead of time
+CREATE TABLE IF NOT EXISTS contest_schedule
+(
+    id           INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name         VARCHAR(255) NOT NULL,
+    spec         VARCHAR(64)  NOT NULL,
+    duration     INT          NOT NULL,
+    oj_id        INT          NOT NULL DEFAULT 0,
+    scoring_mode VARCHAR(16)  NOT NULL DEFAULT '',
+    group_id     INT          NOT NULL,
+    team_ids     TEXT         NOT NULL,
+    lead_days    INT          NOT NULL DEFAULT 7,
+    is_enable    BOOLEAN      NOT NULL DEFAULT TRUE,
+    last_time    DATETIME     NOT NULL,
+    FOREIGN KEY (group_id) REFERENCES contest_group (id)
+);
+
+-- contests created by schedules
+CREATE TABLE IF NOT EXISTS contest_schedule_rel
+(
+    schedule_id   INT     NOT NULL,
+    contest_id    INT     NOT NULL PRIMARY KEY,
+    is_dispatched BOOLEAN NOT NULL DEFAULT FALSE,
+    FOREIGN KEY (schedule_id) REFERENCES contest_schedule (id) ON DELETE CASCADE,
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE
+);
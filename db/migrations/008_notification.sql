@@ -0,0 +1,12 @@
+-- email of users, used by email notifications
+ALTER TABLE user ADD COLUMN email VARCHAR(255) NOT NULL DEFAULT '';
+
+-- notifications that have been sent, each kind is sent once per contest
+CREATE TABLE IF NOT EXISTS contest_notification
+(
+    contest_id  INT         NOT NULL,
+    kind        VARCHAR(16) NOT NULL,
+    create_time DATETIME    NOT NULL,
+    PRIMARY KEY (contest_id, kind),
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE
+);
@@ -0,0 +1,12 @@
+-- in-app notifications of users
+CREATE TABLE IF NOT EXISTS user_notification
+(
+    id          INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    username    VARCHAR(255) NOT NULL,
+    kind        VARCHAR(16)  NOT NULL,
+    content     TEXT         NOT NULL,
+    is_read     BOOLEAN      NOT NULL DEFAULT FALSE,
+    create_time DATETIME     NOT NULL,
+    INDEX (username, is_read),
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
@@ -0,0 +1,26 @@
+-- subscriptions of domain events
+CREATE TABLE IF NOT EXISTS webhook
+(
+    id        INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    url       VARCHAR(512) NOT NULL,
+    secret    VARCHAR(255) NOT NULL,
+    events    TEXT         NOT NULL,
+    is_enable BOOLEAN      NOT NULL DEFAULT TRUE
+);
+
+-- log of each delivery attempt
+CREATE TABLE IF NOT EXISTS webhook_delivery
+(
+    id          INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    webhook_id  INT          NOT NULL,
+    delivery_id VARCHAR(64)  NOT NULL,
+    event       VARCHAR(64)  NOT NULL,
+    payload     MEDIUMTEXT   NOT NULL,
+    attempt     INT          NOT NULL,
+    status_code INT          NOT NULL DEFAULT 0,
+    error       VARCHAR(512) NOT NULL DEFAULT '',
+    is_success  BOOLEAN      NOT NULL,
+    create_time DATETIME     NOT NULL,
+    INDEX (webhook_id),
+    FOREIGN KEY (webhook_id) REFERENCES webhook (id) ON DELETE CASCADE
+);
@@ -0,0 +1,16 @@
+-- problem catalog, problems of contests are added automatically
+CREATE TABLE IF NOT EXISTS problem
+(
+    oj_id       INT          NOT NULL,
+    pid         VARCHAR(64)  NOT NULL,
+    title       VARCHAR(255) NOT NULL DEFAULT '',
+    difficulty  INT          NOT NULL DEFAULT 0,
+    tags        TEXT         NOT NULL,
+    update_time DATETIME     NOT NULL,
+    PRIMARY KEY (oj_id, pid),
+    INDEX (difficulty)
+);
+
+-- existing problems of contests
+INSERT IGNORE INTO problem(oj_id, pid, title, difficulty, tags, update_time)
+SELECT DISTINCT oj_id, pid, '', 0, '[]', NOW() FROM contest_problem;
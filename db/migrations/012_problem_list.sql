@@ -0,0 +1,31 @@
+-- training problem lists
+CREATE TABLE IF NOT EXISTS problem_list
+(
+    id          INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    name        VARCHAR(255) NOT NULL,
+    description TEXT         NOT NULL,
+    create_time DATETIME     NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS problem_list_item
+(
+    list_id INT         NOT NULL,
+    idx     INT         NOT NULL,
+    oj_id   INT         NOT NULL,
+    pid     VARCHAR(64) NOT NULL,
+    PRIMARY KEY (list_id, oj_id, pid),
+    FOREIGN KEY (list_id) REFERENCES problem_list (id) ON DELETE CASCADE
+);
+
+-- a list is assigned to a team group (group_id > 0) or a user (username != '')
+CREATE TABLE IF NOT EXISTS problem_list_assignment
+(
+    id       INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    list_id  INT          NOT NULL,
+    group_id INT          NOT NULL DEFAULT 0,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    deadline DATETIME     NOT NULL,
+    INDEX (group_id),
+    INDEX (username),
+    FOREIGN KEY (list_id) REFERENCES problem_list (id) ON DELETE CASCADE
+);
@@ -0,0 +1,9 @@
+-- detail of judge result, verdict is one of enum/verdict (empty for old submissions)
+-- run_time is in milliseconds and memory is in KB
+ALTER TABLE submission
+    ADD COLUMN verdict  VARCHAR(16) NOT NULL DEFAULT '',
+    ADD COLUMN language VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN run_time INT         NOT NULL DEFAULT 0,
+    ADD COLUMN memory   INT         NOT NULL DEFAULT 0;
+
+UPDATE submission SET verdict = 'OK' WHERE is_accepted;
@@ -0,0 +1,18 @@
+-- bindings of oj accounts with validity periods, end_time is NULL for the current binding
+-- oj_user_rel still keeps the current account of each user
+CREATE TABLE IF NOT EXISTS oj_account_history
+(
+    id         INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    username   VARCHAR(255) NOT NULL,
+    oj_id      INT          NOT NULL,
+    account    VARCHAR(255) NOT NULL,
+    begin_time DATETIME     NOT NULL,
+    end_time   DATETIME,
+    INDEX (username, oj_id),
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
+
+INSERT INTO oj_account_history(username, oj_id, account, begin_time)
+SELECT username, oj_id, account, NOW()
+FROM oj_user_rel
+WHERE account != '';
@@ -0,0 +1,20 @@
+-- accounts must be verified before they are counted in official stats
+ALTER TABLE oj_user_rel ADD COLUMN is_verified BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- accounts bound before verification is introduced are trusted
+UPDATE oj_user_rel SET is_verified = TRUE;
+
+-- pending ownership challenge of the current account, at most one per user and oj
+-- method is profile (token in organization or first name) or compile_error (CE on pid)
+CREATE TABLE IF NOT EXISTS account_challenge
+(
+    username    VARCHAR(255) NOT NULL,
+    oj_id       INT          NOT NULL,
+    account     VARCHAR(255) NOT NULL,
+    method      VARCHAR(16)  NOT NULL,
+    token       VARCHAR(64)  NOT NULL,
+    pid         VARCHAR(32)  NOT NULL DEFAULT '',
+    create_time DATETIME     NOT NULL,
+    PRIMARY KEY (username, oj_id),
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
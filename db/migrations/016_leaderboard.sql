@@ -0,0 +1,25 @@
+-- finalized club-wide leaderboards of a week, month or season
+CREATE TABLE IF NOT EXISTS leaderboard
+(
+    id          INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    kind        VARCHAR(16)  NOT NULL,
+    name        VARCHAR(255) NOT NULL,
+    begin_time  DATETIME     NOT NULL,
+    end_time    DATETIME     NOT NULL,
+    create_time DATETIME     NOT NULL,
+    UNIQUE (kind, begin_time)
+);
+
+-- prev_ranking is the ranking in the previous period of the same kind, 0 if not ranked
+CREATE TABLE IF NOT EXISTS leaderboard_entry
+(
+    leaderboard_id INT          NOT NULL,
+    category       VARCHAR(16)  NOT NULL,
+    username       VARCHAR(255) NOT NULL,
+    ranking        INT          NOT NULL,
+    value          INT          NOT NULL,
+    prev_ranking   INT          NOT NULL DEFAULT 0,
+    PRIMARY KEY (leaderboard_id, category, username),
+    FOREIGN KEY (leaderboard_id) REFERENCES leaderboard (id) ON DELETE CASCADE,
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
@@ -0,0 +1,14 @@
+-- members flagged by the inactivity scan, coaches acknowledge them after following up
+CREATE TABLE IF NOT EXISTS member_alert
+(
+    id          INT          NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    username    VARCHAR(255) NOT NULL,
+    kind        VARCHAR(16)  NOT NULL,
+    detail      VARCHAR(255) NOT NULL,
+    create_time DATETIME     NOT NULL,
+    is_acked    BOOLEAN      NOT NULL DEFAULT FALSE,
+    ack_note    VARCHAR(255) NOT NULL DEFAULT '',
+    ack_time    DATETIME,
+    INDEX (username, kind),
+    FOREIGN KEY (username) REFERENCES user (username) ON DELETE CASCADE
+);
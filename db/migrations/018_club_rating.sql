@@ -0,0 +1,17 @@
+-- change of rating in each contest, the first one of a user is against the initial rating (0 for external oj)
+ALTER TABLE rating ADD COLUMN delta INT NOT NULL DEFAULT 0;
+
+UPDATE rating
+    JOIN (SELECT username, oj_id, contest_time,
+                 rating - IFNULL(LAG(rating) OVER (PARTITION BY username, oj_id ORDER BY contest_time), 0) AS d
+          FROM rating) tmp
+    ON rating.username = tmp.username AND rating.oj_id = tmp.oj_id AND rating.contest_time = tmp.contest_time
+SET rating.delta = tmp.d;
+
+-- contests which have been rated by the club rating (oj_id = 0 in rating)
+CREATE TABLE IF NOT EXISTS club_rating_contest
+(
+    contest_id INT      NOT NULL PRIMARY KEY,
+    rate_time  DATETIME NOT NULL,
+    FOREIGN KEY (contest_id) REFERENCES contest (id) ON DELETE CASCADE
+);
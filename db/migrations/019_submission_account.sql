@@ -0,0 +1,3 @@
+-- account of the oj which made the submission, so that an unverified binding doesn't hide submissions of previous ones
+-- submissions added before it are all of verified accounts
+ALTER TABLE submission ADD COLUMN account VARCHAR(255) NOT NULL DEFAULT '';
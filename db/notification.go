@@ -0,0 +1,33 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// kinds of contest notification
+const (
+	NotificationReminder = "reminder" // sent before contest starts
+	NotificationResult   = "result"   // sent after contest ends
+)
+
+// GetUnnotifiedContests return contests (without problems) which have not been notified of kind
+// start time (for reminder) or end time (for result) of the contests is during [begin, end]
+func GetUnnotifiedContests(ctx context.Context, kind string, begin, end time.Time) []Contest {
+	column := "start_time"
+	if kind == NotificationResult {
+		column = "DATE_ADD(start_time, INTERVAL duration MINUTE)"
+	}
+	query := `SELECT * FROM contest
+WHERE ` + column + ` BETWEEN ? AND ?
+AND id NOT IN (SELECT contest_id FROM contest_notification WHERE kind = ?)
+ORDER BY start_time`
+	ret := make([]Contest, 0)
+	mustSelect(ctx, &ret, query, begin, end, kind)
+	return ret
+}
+
+func AddContestNotification(ctx context.Context, contestId int, kind string) {
+	query := "INSERT IGNORE INTO contest_notification(contest_id, kind, create_time) VALUES(?, ?, ?)"
+	mustExec(ctx, query, contestId, kind, time.Now())
+}
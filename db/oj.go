@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"database/sql"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 )
 
@@ -56,9 +58,10 @@ func GetAllEnableOJ(ctx context.Context) []OJ {
 }
 
 type Account struct {
-	Username string `json:"username" db:"username"`
-	OjId     int    `json:"oj_id" db:"oj_id"`
-	Account  string `json:"account" db:"account"`
+	Username   string `json:"username" db:"username"`
+	OjId       int    `json:"oj_id" db:"oj_id"`
+	Account    string `json:"account" db:"account"`
+	IsVerified bool   `json:"is_verified" db:"is_verified"`
 }
 
 func GetAccount(ctx context.Context, username string, ojId int) (account string) {
@@ -85,17 +88,80 @@ func GetAccountsByUsername(ctx context.Context, username string) []Account {
 }
 
 // UpdAccount update if account already exists, otherwise insert
-// this will cause the user's submissions on the OJ to be cleared
-func UpdAccount(ctx context.Context, account Account) {
+// the previous binding is closed in oj_account_history and submissions of it are kept
+// the new account is unverified and the pending challenge is cleared
+// return whether the account is changed
+func UpdAccount(ctx context.Context, account Account) (changed bool) {
 	tx := instance.MustBeginTx(ctx, nil)
 	defer tx.Rollback()
-	// clear submissions
-	query := "DELETE FROM submission WHERE username=:username AND account_oj_id=:oj_id"
-	mustNamedExecTx(tx, ctx, query, account)
-	query = `INSERT INTO oj_user_rel(oj_id, username, account)
-VALUES(:oj_id, :username, :account) ON DUPLICATE KEY UPDATE account=VALUES(account)`
+	var prev []string
+	query := "SELECT account FROM oj_user_rel WHERE username=? AND oj_id=? FOR UPDATE"
+	if err := tx.SelectContext(ctx, &prev, query, account.Username, account.OjId); err != nil {
+		panic(err)
+	}
+	if len(prev) > 0 && prev[0] == account.Account {
+		return false
+	}
+	now := time.Now()
+	query = "UPDATE oj_account_history SET end_time=? WHERE username=? AND oj_id=? AND end_time IS NULL"
+	mustExecTx(tx, ctx, query, now, account.Username, account.OjId)
+	if account.Account != "" {
+		query = "INSERT INTO oj_account_history(username, oj_id, account, begin_time) VALUES(?, ?, ?, ?)"
+		mustExecTx(tx, ctx, query, account.Username, account.OjId, account.Account, now)
+	}
+	query = "DELETE FROM account_challenge WHERE username=? AND oj_id=?"
+	mustExecTx(tx, ctx, query, account.Username, account.OjId)
+	query = `INSERT INTO oj_user_rel(oj_id, username, account, is_verified)
+VALUES(:oj_id, :username, :account, FALSE) ON DUPLICATE KEY UPDATE account=VALUES(account), is_verified=FALSE`
 	mustNamedExecTx(tx, ctx, query, account)
 	mustCommit(tx)
+	return true
+}
+
+// AccountBinding is a binding of oj account in a period, EndTime is nil for the current binding
+type AccountBinding struct {
+	Id        int       `json:"id" db:"id"`
+	Username  string    `json:"username" db:"username"`
+	OjId      int       `json:"oj_id" db:"oj_id"`
+	Account   string    `json:"account" db:"account"`
+	BeginTime Datetime  `json:"begin_time" db:"begin_time"`
+	EndTime   *Datetime `json:"end_time" db:"end_time"`
+}
+
+type dbAccountBinding struct {
+	Id        int          `db:"id"`
+	Username  string       `db:"username"`
+	OjId      int          `db:"oj_id"`
+	Account   string       `db:"account"`
+	BeginTime time.Time    `db:"begin_time"`
+	EndTime   sql.NullTime `db:"end_time"`
+}
+
+func (b *dbAccountBinding) jsonType() AccountBinding {
+	ret := AccountBinding{
+		Id:        b.Id,
+		Username:  b.Username,
+		OjId:      b.OjId,
+		Account:   b.Account,
+		BeginTime: Datetime(b.BeginTime),
+	}
+	if b.EndTime.Valid {
+		t := Datetime(b.EndTime.Time)
+		ret.EndTime = &t
+	}
+	return ret
+}
+
+// GetAccountHistory return all bindings of the user, newest first
+func GetAccountHistory(ctx context.Context, username string) []AccountBinding {
+	query := "SELECT * FROM oj_account_history WHERE username=? ORDER BY oj_id, begin_time DESC, id DESC"
+	data := make([]dbAccountBinding, 0)
+	mustSelect(ctx, &data, query, username)
+	ret := make([]AccountBinding, 0)
+	for _, x := range data {
+		ret = append(ret, x.jsonType())
+	}
+	return ret
 }
 
 func GetAllAccounts(ctx context.Context) []Account {
@@ -137,6 +203,17 @@ func GetAllAccountsGroupByOJ(ctx context.Context) []ojAccount {
 	return ret
 }
 
+// GetVerifiedAccountsMap is GetAllAccountsMap without unverified accounts
+func GetVerifiedAccountsMap(ctx context.Context) map[Account]string {
+	ret := make(map[Account]string)
+	for _, ac := range GetAllAccounts(ctx) {
+		if ac.IsVerified {
+			ret[Account{OjId: ac.OjId, Account: ac.Account}] = ac.Username
+		}
+	}
+	return ret
+}
+
 func GetAllAccountsMap(ctx context.Context) map[Account]string {
 	ret := make(map[Account]string)
 	accounts := GetAllAccounts(ctx)
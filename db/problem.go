@@ -0,0 +1,221 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"zuccacm-server/utils"
+)
+
+// ProblemMeta is a problem of an OJ, which can be used by many contests
+// Difficulty is the rating of problem (like codeforces), 0 means unknown
+type ProblemMeta struct {
+	OjId       int      `json:"oj_id" db:"oj_id"`
+	Pid        string   `json:"pid" db:"pid"`
+	Title      string   `json:"title" db:"title"`
+	Difficulty int      `json:"difficulty" db:"difficulty"`
+	Tags       []string `json:"tags"`
+	UpdateTime Datetime `json:"update_time" db:"update_time"`
+	ProblemURL string   `json:"problem_url,omitempty"`
+}
+
+type dbProblemMeta struct {
+	OjId       int       `db:"oj_id"`
+	Pid        string    `db:"pid"`
+	Title      string    `db:"title"`
+	Difficulty int       `db:"difficulty"`
+	Tags       string    `db:"tags"`
+	UpdateTime time.Time `db:"update_time"`
+}
+
+func (p *ProblemMeta) dbType() *dbProblemMeta {
+	if p.Tags == nil {
+		p.Tags = make([]string, 0)
+	}
+	tags, err := json.Marshal(p.Tags)
+	if err != nil {
+		panic(err)
+	}
+	return &dbProblemMeta{
+		OjId:       p.OjId,
+		Pid:        p.Pid,
+		Title:      p.Title,
+		Difficulty: p.Difficulty,
+		Tags:       string(tags),
+		UpdateTime: time.Time(p.UpdateTime),
+	}
+}
+
+func (p *dbProblemMeta) jsonType() *ProblemMeta {
+	ret := &ProblemMeta{
+		OjId:       p.OjId,
+		Pid:        p.Pid,
+		Title:      p.Title,
+		Difficulty: p.Difficulty,
+		Tags:       make([]string, 0),
+		UpdateTime: Datetime(p.UpdateTime),
+	}
+	if err := json.Unmarshal([]byte(p.Tags), &ret.Tags); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// ProblemSolver is a user who has solved the problem
+type ProblemSolver struct {
+	Username   string   `json:"username" db:"username"`
+	Nickname   string   `json:"nickname" db:"nickname"`
+	SolvedTime Datetime `json:"solved_time" db:"solved_time"`
+}
+
+type dbProblemSolver struct {
+	Username   string    `db:"username"`
+	Nickname   string    `db:"nickname"`
+	SolvedTime time.Time `db:"solved_time"`
+}
+
+func (s *dbProblemSolver) jsonType() *ProblemSolver {
+	return &ProblemSolver{
+		Username:   s.Username,
+		Nickname:   s.Nickname,
+		SolvedTime: Datetime(s.SolvedTime),
+	}
+}
+
+// ProblemFilter of SearchProblems, zero value means no limit
+type ProblemFilter struct {
+	OjId          int
+	Keyword       string // part of title or pid
+	Tag           string
+	MinDifficulty int
+	MaxDifficulty int
+}
+
+// addProblemsTx add problems of contest into catalog without metadata, existing ones are kept
+func addProblemsTx(tx *sqlx.Tx, ctx context.Context, problems []Problem) {
+	if len(problems) == 0 {
+		return
+	}
+	query := `INSERT IGNORE INTO problem(oj_id, pid, title, difficulty, tags, update_time)
+VALUES(:oj_id, :pid, '', 0, '[]', NOW())`
+	mustNamedExecTx(tx, ctx, query, problems)
+}
+
+// UpsertProblems add problems or update their metadata
+func UpsertProblems(ctx context.Context, problems []ProblemMeta) {
+	if len(problems) == 0 {
+		return
+	}
+	data := make([]dbProblemMeta, 0)
+	now := Datetime(time.Now())
+	for _, p := range problems {
+		p.UpdateTime = now
+		data = append(data, *p.dbType())
+	}
+	query := `INSERT INTO problem(oj_id, pid, title, difficulty, tags, update_time)
+VALUES(:oj_id, :pid, :title, :difficulty, :tags, :update_time)
+ON DUPLICATE KEY UPDATE title=VALUES(title), difficulty=VALUES(difficulty), tags=VALUES(tags), update_time=VALUES(update_time)`
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	groupSize := 2000
+	for i := 0; i < len(data); i += groupSize {
+		mustNamedExecTx(tx, ctx, query, data[i:utils.Min(i+groupSize, len(data))])
+	}
+	mustCommit(tx)
+}
+
+// GetProblemMeta return nil if the problem is not in catalog
+func GetProblemMeta(ctx context.Context, ojId int, pid string) *ProblemMeta {
+	var data []dbProblemMeta
+	mustSelect(ctx, &data, "SELECT * FROM problem WHERE oj_id = ? AND pid = ?", ojId, pid)
+	if len(data) == 0 {
+		return nil
+	}
+	return data[0].jsonType()
+}
+
+// SearchProblems return problems meeting the filter, ordered by oj and pid
+func SearchProblems(ctx context.Context, f ProblemFilter, page Page) []ProblemMeta {
+	query := "SELECT * FROM problem WHERE 1=1"
+	args := make([]interface{}, 0)
+	if f.OjId > 0 {
+		query += " AND oj_id = ?"
+		args = append(args, f.OjId)
+	}
+	if f.Keyword != "" {
+		query += " AND (title LIKE ? OR pid LIKE ?)"
+		args = append(args, "%"+f.Keyword+"%", f.Keyword+"%")
+	}
+	if f.Tag != "" {
+		// tags is a json array in text
+		b, _ := json.Marshal(f.Tag)
+		query += " AND tags LIKE ?"
+		args = append(args, "%"+string(b)+"%")
+	}
+	if f.MinDifficulty > 0 {
+		query += " AND difficulty >= ?"
+		args = append(args, f.MinDifficulty)
+	}
+	if f.MaxDifficulty > 0 {
+		query += " AND difficulty <= ?"
+		args = append(args, f.MaxDifficulty)
+	}
+	query += " ORDER BY oj_id, pid"
+	var data []dbProblemMeta
+	mustSelect(ctx, &data, page.query(query), args...)
+	ret := make([]ProblemMeta, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+// GetContestsByProblem return contests (without problems) which use the problem
+func GetContestsByProblem(ctx context.Context, ojId int, pid string) []Contest {
+	query := `SELECT * FROM contest WHERE id IN
+(SELECT contest_id FROM contest_problem WHERE oj_id = ? AND pid = ?)
+ORDER BY start_time DESC`
+	ret := make([]Contest, 0)
+	mustSelect(ctx, &ret, query, ojId, pid)
+	for i := range ret {
+		ret[i].Problems = make([]Problem, 0)
+		ret[i].Groups = make([]int, 0)
+		ret[i].Teams = make([]int, 0)
+	}
+	return ret
+}
+
+// GetProblemSolvers return users who have solved the problem, in order of first accepted time
+func GetProblemSolvers(ctx context.Context, ojId int, pid string) []ProblemSolver {
+	query := `SELECT user.username AS username, nickname, MIN(create_time) AS solved_time
+FROM submission, user
+WHERE submission.username = user.username
+AND oj_id = ? AND pid = ? AND is_accepted AND ` + verifiedSubmissionSQL + `
+GROUP BY user.username, nickname
+ORDER BY solved_time`
+	var data []dbProblemSolver
+	mustSelect(ctx, &data, query, ojId, pid)
+	ret := make([]ProblemSolver, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+// GetSolvedProblems return problems (only OjId and Pid) solved by any of users
+func GetSolvedProblems(ctx context.Context, usernames []string) []ProblemMeta {
+	ret := make([]ProblemMeta, 0)
+	if len(usernames) == 0 {
+		return ret
+	}
+	query, args, err := sqlx.In(`SELECT DISTINCT oj_id, pid FROM submission
+WHERE is_accepted AND username IN (?)`, usernames)
+	if err != nil {
+		panic(err)
+	}
+	mustSelect(ctx, &ret, instance.Rebind(query), args...)
+	return ret
+}
@@ -0,0 +1,210 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"zuccacm-server/enum/errorx"
+)
+
+// status of a problem in an assignment
+const (
+	AssignmentSolved   = "solved"   // solved before deadline
+	AssignmentLate     = "late"     // solved after deadline
+	AssignmentUnsolved = "unsolved" // not solved yet
+)
+
+type ProblemListItem struct {
+	ListId     int    `json:"list_id" db:"list_id"`
+	Idx        int    `json:"idx" db:"idx"`
+	OjId       int    `json:"oj_id" db:"oj_id"`
+	Pid        string `json:"pid" db:"pid"`
+	ProblemURL string `json:"problem_url,omitempty"`
+}
+
+// ProblemList is an ordered set of problems
+type ProblemList struct {
+	Id          int               `json:"id" db:"id"`
+	Name        string            `json:"name" db:"name"`
+	Description string            `json:"description" db:"description"`
+	CreateTime  Datetime          `json:"create_time" db:"create_time"`
+	Problems    []ProblemListItem `json:"problems"`
+}
+
+// Assignment assign a problem list to a team group (GroupId > 0) or a user (Username != "")
+type Assignment struct {
+	Id       int      `json:"id" db:"id"`
+	ListId   int      `json:"list_id" db:"list_id"`
+	GroupId  int      `json:"group_id" db:"group_id"`
+	Username string   `json:"username" db:"username"`
+	Deadline Datetime `json:"deadline" db:"deadline"`
+}
+
+// FirstAccepted is the first accepted time of a user on a problem
+type FirstAccepted struct {
+	Username   string    `db:"username"`
+	OjId       int       `db:"oj_id"`
+	Pid        string    `db:"pid"`
+	SolvedTime time.Time `db:"solved_time"`
+}
+
+// GetProblemLists return lists without problems
+func GetProblemLists(ctx context.Context) []ProblemList {
+	ret := make([]ProblemList, 0)
+	mustSelect(ctx, &ret, "SELECT * FROM problem_list ORDER BY id DESC")
+	for i := range ret {
+		ret[i].Problems = make([]ProblemListItem, 0)
+	}
+	return ret
+}
+
+// GetProblemListById return list with problems in order
+func GetProblemListById(ctx context.Context, id int) ProblemList {
+	var l ProblemList
+	err := instance.GetContext(ctx, &l, "SELECT * FROM problem_list WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		panic(errorx.ErrNotFound.New())
+	}
+	if err != nil {
+		panic(err)
+	}
+	l.Problems = make([]ProblemListItem, 0)
+	mustSelect(ctx, &l.Problems, "SELECT * FROM problem_list_item WHERE list_id = ? ORDER BY idx", id)
+	return l
+}
+
+func addProblemListItemsTx(tx *sqlx.Tx, ctx context.Context, l ProblemList) {
+	if len(l.Problems) == 0 {
+		return
+	}
+	for i := range l.Problems {
+		l.Problems[i].ListId = l.Id
+		l.Problems[i].Idx = i
+	}
+	query := "INSERT INTO problem_list_item(list_id, idx, oj_id, pid) VALUES(:list_id, :idx, :oj_id, :pid)"
+	mustNamedExecTx(tx, ctx, query, l.Problems)
+	problems := make([]Problem, 0)
+	for _, p := range l.Problems {
+		problems = append(problems, Problem{OjId: p.OjId, Pid: p.Pid})
+	}
+	addProblemsTx(tx, ctx, problems)
+}
+
+// AddProblemList return the new list with ProblemList.Id, problems are ordered as given
+func AddProblemList(ctx context.Context, l ProblemList) ProblemList {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	res := tx.MustExecContext(ctx, "INSERT INTO problem_list(name, description, create_time) VALUES(?, ?, ?)",
+		l.Name, l.Description, time.Now())
+	id, err := res.LastInsertId()
+	if err != nil {
+		panic(err)
+	}
+	l.Id = int(id)
+	addProblemListItemsTx(tx, ctx, l)
+	mustCommit(tx)
+	return l
+}
+
+// UpdProblemList update name, description and replace problems
+func UpdProblemList(ctx context.Context, l ProblemList) {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	mustExecTx(tx, ctx, "UPDATE problem_list SET name=?, description=? WHERE id=?", l.Name, l.Description, l.Id)
+	mustExecTx(tx, ctx, "DELETE FROM problem_list_item WHERE list_id=?", l.Id)
+	addProblemListItemsTx(tx, ctx, l)
+	mustCommit(tx)
+}
+
+func DelProblemList(ctx context.Context, id int) {
+	mustExec(ctx, "DELETE FROM problem_list WHERE id=?", id)
+}
+
+func AddAssignment(ctx context.Context, a Assignment) {
+	query := "INSERT INTO problem_list_assignment(list_id, group_id, username, deadline) VALUES(?, ?, ?, ?)"
+	mustExec(ctx, query, a.ListId, a.GroupId, a.Username, time.Time(a.Deadline))
+}
+
+func DelAssignment(ctx context.Context, id int) {
+	mustExec(ctx, "DELETE FROM problem_list_assignment WHERE id=?", id)
+}
+
+func GetAssignmentById(ctx context.Context, id int) Assignment {
+	var a Assignment
+	err := instance.GetContext(ctx, &a, "SELECT * FROM problem_list_assignment WHERE id = ?", id)
+	if err == sql.ErrNoRows {
+		panic(errorx.ErrNotFound.New())
+	}
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+func GetAssignmentsByList(ctx context.Context, listId int) []Assignment {
+	ret := make([]Assignment, 0)
+	mustSelect(ctx, &ret, "SELECT * FROM problem_list_assignment WHERE list_id = ? ORDER BY deadline DESC", listId)
+	return ret
+}
+
+// GetAssignmentsByUser return assignments to the user directly or to team groups of the user
+func GetAssignmentsByUser(ctx context.Context, username string) []Assignment {
+	query := `SELECT * FROM problem_list_assignment
+WHERE username = ? OR group_id IN
+(
+    SELECT group_id FROM team_group_rel, team_user_rel
+    WHERE team_group_rel.team_id = team_user_rel.team_id AND username = ?
+)
+ORDER BY deadline DESC`
+	ret := make([]Assignment, 0)
+	mustSelect(ctx, &ret, query, username, username)
+	return ret
+}
+
+// GetAssignmentUsers return enabled users who should finish the assignment
+func GetAssignmentUsers(ctx context.Context, a Assignment) []UserSimple {
+	ret := make([]UserSimple, 0)
+	if a.GroupId == 0 {
+		mustSelect(ctx, &ret, "SELECT username, nickname FROM user WHERE username = ?", a.Username)
+		return ret
+	}
+	query := `SELECT DISTINCT user.username, nickname
+FROM user, team_user_rel, team_group_rel
+WHERE user.username = team_user_rel.username AND team_user_rel.team_id = team_group_rel.team_id
+AND group_id = ? AND is_enable
+ORDER BY user.username`
+	mustSelect(ctx, &ret, query, a.GroupId)
+	return ret
+}
+
+// GetFirstAccepted return first accepted time of users on problems
+func GetFirstAccepted(ctx context.Context, usernames []string, problems []ProblemListItem) []FirstAccepted {
+	ret := make([]FirstAccepted, 0)
+	if len(usernames) == 0 || len(problems) == 0 {
+		return ret
+	}
+	pids := make([]string, 0)
+	want := make(map[ProblemListItem]bool)
+	for _, p := range problems {
+		pids = append(pids, p.Pid)
+		want[ProblemListItem{OjId: p.OjId, Pid: p.Pid}] = true
+	}
+	query, args, err := sqlx.In(`SELECT username, oj_id, pid, MIN(create_time) AS solved_time
+FROM submission
+WHERE is_accepted AND username IN (?) AND pid IN (?) AND `+verifiedSubmissionSQL+`
+GROUP BY username, oj_id, pid`, usernames, pids)
+	if err != nil {
+		panic(err)
+	}
+	var data []FirstAccepted
+	mustSelect(ctx, &data, instance.Rebind(query), args...)
+	for _, x := range data {
+		if want[ProblemListItem{OjId: x.OjId, Pid: x.Pid}] {
+			ret = append(ret, x)
+		}
+	}
+	return ret
+}
@@ -2,6 +2,7 @@ package db
 
 import (
 	"context"
+	"sort"
 	"time"
 )
 
@@ -13,23 +14,60 @@ type Rating struct {
 	ContestTime time.Time `db:"contest_time"`
 	ContestName string    `db:"contest_name"`
 	ContestURL  string    `db:"contest_url"`
+	Delta       int       `db:"delta"`
 }
 
-func UpdRating(ctx context.Context, username string, ojId int, ratings []Rating) {
+// UpdRating replace the rating history of the user on the oj, Delta is calculated by the order of ContestTime
+// return false and keep the history if ratings are the same as it
+func UpdRating(ctx context.Context, username string, ojId int, ratings []Rating) (changed bool) {
 	if len(ratings) == 0 {
-		return
+		return false
 	}
 	tx := instance.MustBeginTx(ctx, nil)
 	defer tx.Rollback()
+	sort.SliceStable(ratings, func(i, j int) bool {
+		return ratings[i].ContestTime.Before(ratings[j].ContestTime)
+	})
+	var old []Rating
 	query := `
+SELECT rating, contest_rank, contest_time FROM rating
+WHERE username=? AND oj_id=?
+ORDER BY contest_time
+FOR UPDATE`
+	if err := tx.SelectContext(ctx, &old, query, username, ojId); err != nil {
+		panic(err)
+	}
+	if sameRatings(old, ratings) {
+		return false
+	}
+	query = `
 DELETE FROM rating
 WHERE username=? AND oj_id=?`
 	mustExecTx(tx, ctx, query, username, ojId)
-	query = `
-INSERT INTO rating(username, oj_id, rating, contest_rank, contest_time, contest_name, contest_url)
-VALUES(:username, :oj_id, :rating, :contest_rank, :contest_time, :contest_name, :contest_url)`
+	for i := range ratings {
+		ratings[i].Delta = ratings[i].Rating
+		if i > 0 {
+			ratings[i].Delta -= ratings[i-1].Rating
+		}
+	}
+	query = addRatingSQL
 	mustNamedExecTx(tx, ctx, query, ratings)
 	mustCommit(tx)
+	return true
+}
+
+// sameRatings compare rating histories sorted by ContestTime
+func sameRatings(a, b []Rating) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Rating != b[i].Rating || a[i].ContestRank != b[i].ContestRank ||
+			a[i].ContestTime.Unix() != b[i].ContestTime.Unix() {
+			return false
+		}
+	}
+	return true
 }
 
 func GetRating(ctx context.Context, username string, ojId int) int {
@@ -74,6 +112,8 @@ type userRating struct {
 	MaxRating int    `db:"max_rating"`
 }
 
+// GetOfficialUserRatings return ratings of official users
+// ratings of unverified accounts are never added, see updRating
 func GetOfficialUserRatings(ctx context.Context, ojId int) []userRating {
 	query := `
 SELECT username,
@@ -86,11 +126,18 @@ IFNULL((
     WHERE username = official_user.username AND oj_id = ? AND contest_time =
     (
         SELECT MAX(contest_time) FROM rating
-        WHERE username = official_user.username AND contest_rank > 0
+        WHERE username = official_user.username AND oj_id = ? AND contest_rank > 0
     )
 ), 0) AS rating
 FROM official_user`
 	data := make([]userRating, 0)
-	mustSelect(ctx, &data, query, ojId, ojId)
+	mustSelect(ctx, &data, query, ojId, ojId, ojId)
 	return data
 }
+
+// GetRatedOJs return ids of ojs on which the user has rating history
+func GetRatedOJs(ctx context.Context, username string) []int {
+	ret := make([]int, 0)
+	mustSelect(ctx, &ret, "SELECT DISTINCT oj_id FROM rating WHERE username=? ORDER BY oj_id", username)
+	return ret
+}
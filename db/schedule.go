@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// ContestSchedule create contests of a contest group periodically
+// Spec is a standard cron spec of start time, such as '0 19 * * 6' (every Saturday 19:00)
+// contests starting in the next LeadDays days are created ahead of time
+// LastTime is the start time of the last contest created
+type ContestSchedule struct {
+	Id          int      `json:"id" db:"id"`
+	Name        string   `json:"name" db:"name"`
+	Spec        string   `json:"spec" db:"spec"`
+	Duration    int      `json:"duration" db:"duration"`
+	OjId        int      `json:"oj_id" db:"oj_id"`
+	ScoringMode string   `json:"scoring_mode" db:"scoring_mode"`
+	GroupId     int      `json:"group_id" db:"group_id"`
+	Teams       []int    `json:"teams"`
+	LeadDays    int      `json:"lead_days" db:"lead_days"`
+	IsEnable    bool     `json:"is_enable" db:"is_enable"`
+	LastTime    Datetime `json:"last_time" db:"last_time"`
+}
+
+type dbContestSchedule struct {
+	Id          int       `db:"id"`
+	Name        string    `db:"name"`
+	Spec        string    `db:"spec"`
+	Duration    int       `db:"duration"`
+	OjId        int       `db:"oj_id"`
+	ScoringMode string    `db:"scoring_mode"`
+	GroupId     int       `db:"group_id"`
+	Teams       string    `db:"team_ids"`
+	LeadDays    int       `db:"lead_days"`
+	IsEnable    bool      `db:"is_enable"`
+	LastTime    time.Time `db:"last_time"`
+}
+
+func (s *ContestSchedule) dbType() *dbContestSchedule {
+	teams, err := json.Marshal(s.Teams)
+	if err != nil {
+		panic(err)
+	}
+	return &dbContestSchedule{
+		Id:          s.Id,
+		Name:        s.Name,
+		Spec:        s.Spec,
+		Duration:    s.Duration,
+		OjId:        s.OjId,
+		ScoringMode: s.ScoringMode,
+		GroupId:     s.GroupId,
+		Teams:       string(teams),
+		LeadDays:    s.LeadDays,
+		IsEnable:    s.IsEnable,
+		LastTime:    time.Time(s.LastTime),
+	}
+}
+
+func (s *dbContestSchedule) jsonType() *ContestSchedule {
+	ret := &ContestSchedule{
+		Id:          s.Id,
+		Name:        s.Name,
+		Spec:        s.Spec,
+		Duration:    s.Duration,
+		OjId:        s.OjId,
+		ScoringMode: s.ScoringMode,
+		GroupId:     s.GroupId,
+		Teams:       make([]int, 0),
+		LeadDays:    s.LeadDays,
+		IsEnable:    s.IsEnable,
+		LastTime:    Datetime(s.LastTime),
+	}
+	if err := json.Unmarshal([]byte(s.Teams), &ret.Teams); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// GetContestSchedules return all schedules if isEnable=false
+func GetContestSchedules(ctx context.Context, isEnable bool) []ContestSchedule {
+	query := "SELECT * FROM contest_schedule"
+	if isEnable {
+		query += " WHERE is_enable"
+	}
+	var data []dbContestSchedule
+	mustSelect(ctx, &data, query)
+	ret := make([]ContestSchedule, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+func AddContestSchedule(ctx context.Context, s ContestSchedule) {
+	query := `INSERT INTO contest_schedule(name, spec, duration, oj_id, scoring_mode, group_id, team_ids, lead_days, is_enable, last_time)
+VALUES(:name, :spec, :duration, :oj_id, :scoring_mode, :group_id, :team_ids, :lead_days, :is_enable, :last_time)`
+	mustNamedExec(ctx, query, s.dbType())
+}
+
+// UpdContestSchedule update settings except last_time
+func UpdContestSchedule(ctx context.Context, s ContestSchedule) {
+	query := `UPDATE contest_schedule
+SET name=:name, spec=:spec, duration=:duration, oj_id=:oj_id, scoring_mode=:scoring_mode,
+group_id=:group_id, team_ids=:team_ids, lead_days=:lead_days, is_enable=:is_enable
+WHERE id=:id`
+	mustNamedExec(ctx, query, s.dbType())
+}
+
+func DelContestSchedule(ctx context.Context, id int) {
+	mustExec(ctx, "DELETE FROM contest_schedule WHERE id=?", id)
+}
+
+// AddScheduledContest add contest created by the schedule and update ContestSchedule.LastTime
+func AddScheduledContest(ctx context.Context, scheduleId int, c Contest) Contest {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	c = addContestTx(tx, ctx, c)
+	mustExecTx(tx, ctx, "INSERT INTO contest_schedule_rel(schedule_id, contest_id, is_dispatched) VALUES(?, ?, false)", scheduleId, c.Id)
+	mustExecTx(tx, ctx, "UPDATE contest_schedule SET last_time=? WHERE id=?", time.Time(c.StartTime), scheduleId)
+	mustCommit(tx)
+	return c
+}
+
+// GetUndispatchedContests return contests created by schedules whose cid has been set but task not dispatched
+func GetUndispatchedContests(ctx context.Context) []Contest {
+	query := `SELECT * FROM contest
+WHERE oj_id > 0 AND cid != ''
+AND id IN (SELECT contest_id FROM contest_schedule_rel WHERE NOT is_dispatched)`
+	ret := make([]Contest, 0)
+	mustSelect(ctx, &ret, query)
+	return ret
+}
+
+func SetContestDispatched(ctx context.Context, contestId int) {
+	mustExec(ctx, "UPDATE contest_schedule_rel SET is_dispatched=true WHERE contest_id=?", contestId)
+}
@@ -0,0 +1,92 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+)
+
+// rules of points for each contest in a contest group
+const (
+	ScoringRank       = "rank"       // points from GroupScoring.RankPoints by rank
+	ScoringSolved     = "solved"     // 1 point for each solved problem
+	ScoringNormalized = "normalized" // 100 * solved / MaxSolved
+)
+
+var defaultRankPoints = []int{100, 75, 60, 50, 45, 40, 36, 32, 29, 26, 24, 22, 20, 18, 16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1}
+
+// GroupScoring is the scoring config of series leaderboard of a contest group
+// the worst DropWorst contests of each user are ignored
+// each upsolved problem is counted as UpsolveWeight solved problem
+type GroupScoring struct {
+	GroupId       int     `json:"group_id" db:"group_id"`
+	Rule          string  `json:"rule" db:"rule"`
+	RankPoints    []int   `json:"rank_points"`
+	DropWorst     int     `json:"drop_worst" db:"drop_worst"`
+	UpsolveWeight float64 `json:"upsolve_weight" db:"upsolve_weight"`
+}
+
+type dbGroupScoring struct {
+	GroupId       int     `db:"group_id"`
+	Rule          string  `db:"rule"`
+	RankPoints    string  `db:"rank_points"`
+	DropWorst     int     `db:"drop_worst"`
+	UpsolveWeight float64 `db:"upsolve_weight"`
+}
+
+func (s *GroupScoring) dbType() *dbGroupScoring {
+	b, err := json.Marshal(s.RankPoints)
+	if err != nil {
+		panic(err)
+	}
+	return &dbGroupScoring{
+		GroupId:       s.GroupId,
+		Rule:          s.Rule,
+		RankPoints:    string(b),
+		DropWorst:     s.DropWorst,
+		UpsolveWeight: s.UpsolveWeight,
+	}
+}
+
+func (s *dbGroupScoring) jsonType() *GroupScoring {
+	ret := &GroupScoring{
+		GroupId:       s.GroupId,
+		Rule:          s.Rule,
+		RankPoints:    make([]int, 0),
+		DropWorst:     s.DropWorst,
+		UpsolveWeight: s.UpsolveWeight,
+	}
+	if err := json.Unmarshal([]byte(s.RankPoints), &ret.RankPoints); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// GetGroupScoring return default config (solved count) if the group has not been configured
+func GetGroupScoring(ctx context.Context, groupId int) GroupScoring {
+	var s dbGroupScoring
+	err := instance.GetContext(ctx, &s, "SELECT * FROM contest_group_scoring WHERE group_id=?", groupId)
+	if err == sql.ErrNoRows {
+		return GroupScoring{
+			GroupId:    groupId,
+			Rule:       ScoringSolved,
+			RankPoints: defaultRankPoints,
+		}
+	}
+	if err != nil {
+		panic(err)
+	}
+	return *s.jsonType()
+}
+
+// UpdGroupScoring update if config already exists, otherwise insert
+func UpdGroupScoring(ctx context.Context, s GroupScoring) {
+	if len(s.RankPoints) == 0 {
+		s.RankPoints = defaultRankPoints
+	}
+	query := `INSERT INTO contest_group_scoring(group_id, rule, rank_points, drop_worst, upsolve_weight)
+VALUES(:group_id, :rule, :rank_points, :drop_worst, :upsolve_weight)
+ON DUPLICATE KEY UPDATE rule=VALUES(rule), rank_points=VALUES(rank_points),
+drop_worst=VALUES(drop_worst), upsolve_weight=VALUES(upsolve_weight)`
+	mustNamedExec(ctx, query, s.dbType())
+}
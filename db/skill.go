@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// ProblemAttempt is the summary of submissions of a user on a problem in catalog
+type ProblemAttempt struct {
+	Username    string
+	OjId        int
+	Pid         string
+	IsSolved    bool
+	Submissions int
+	Difficulty  int
+	Tags        []string
+}
+
+// GetProblemAttempts return attempts of users during [begin, end] on problems in catalog
+func GetProblemAttempts(ctx context.Context, usernames []string, begin, end time.Time) []ProblemAttempt {
+	ret := make([]ProblemAttempt, 0)
+	if len(usernames) == 0 {
+		return ret
+	}
+	query, args, err := sqlx.In(`SELECT submission.username AS username, submission.oj_id AS oj_id, submission.pid AS pid,
+MAX(is_accepted) AS is_solved, COUNT(*) AS submissions, difficulty, tags
+FROM submission, problem
+WHERE submission.oj_id = problem.oj_id AND submission.pid = problem.pid
+AND username IN (?) AND create_time BETWEEN ? AND ? AND `+verifiedSubmissionSQL+`
+GROUP BY submission.username, submission.oj_id, submission.pid, difficulty, tags`, usernames, begin, end)
+	if err != nil {
+		panic(err)
+	}
+	var data []struct {
+		Username    string `db:"username"`
+		OjId        int    `db:"oj_id"`
+		Pid         string `db:"pid"`
+		IsSolved    bool   `db:"is_solved"`
+		Submissions int    `db:"submissions"`
+		Difficulty  int    `db:"difficulty"`
+		Tags        string `db:"tags"`
+	}
+	mustSelect(ctx, &data, instance.Rebind(query), args...)
+	for _, x := range data {
+		a := ProblemAttempt{
+			Username:    x.Username,
+			OjId:        x.OjId,
+			Pid:         x.Pid,
+			IsSolved:    x.IsSolved,
+			Submissions: x.Submissions,
+			Difficulty:  x.Difficulty,
+			Tags:        make([]string, 0),
+		}
+		if err := json.Unmarshal([]byte(x.Tags), &a.Tags); err != nil {
+			panic(err)
+		}
+		ret = append(ret, a)
+	}
+	return ret
+}
@@ -1,8 +1,8 @@
 package db
 
 const (
-	addUserSQL = `INSERT INTO user(username, nickname, is_admin, is_enable, id_card, phone, qq, t_shirt)
- VALUES(:username, :nickname, :is_admin, :is_enable, :id_card, :phone, :qq, :t_shirt)`
+	addUserSQL = `INSERT INTO user(username, nickname, is_admin, is_enable, id_card, phone, qq, t_shirt, email)
+ VALUES(:username, :nickname, :is_admin, :is_enable, :id_card, :phone, :qq, :t_shirt, :email)`
 	updUserEnableSQL         = "UPDATE user SET is_enable=:is_enable WHERE username=:username"
 	updContestGroupEnableSQL = "UPDATE contest_group SET is_enable=:is_enable WHERE id=:id"
 	addContestGroupSQL       = "INSERT INTO contest_group(id, name,is_enable) values(:id, :name, :is_enable)"
@@ -23,6 +23,9 @@ const (
 	addContestGroupRelSQL = "INSERT INTO contest_group_rel(group_id, contest_id) VALUES(:group_id, :contest_id)"
 	addContestTeamRelSQL  = "INSERT INTO contest_team_rel(contest_id, team_id) VALUES(:contest_id, :team_id)"
 
+	addRatingSQL = `INSERT INTO rating(username, oj_id, rating, contest_rank, contest_time, contest_name, contest_url, delta)
+VALUES(:username, :oj_id, :rating, :contest_rank, :contest_time, :contest_name, :contest_url, :delta)`
+
 	getAwardsSQL = `SELECT user.username AS username, medal, award, xcpc_id
 FROM user, team_user_rel, xcpc_team_rel, xcpc
 WHERE user.username=team_user_rel.username
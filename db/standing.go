@@ -0,0 +1,69 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// StandingSnapshot is the standings of a finished contest stored in json
+type StandingSnapshot struct {
+	ContestId  int       `db:"contest_id"`
+	Data       string    `db:"data"`
+	CreateTime time.Time `db:"create_time"`
+}
+
+// GetStandingSnapshot return nil when the contest has no snapshot
+func GetStandingSnapshot(ctx context.Context, contestId int) (ret *StandingSnapshot) {
+	ret = &StandingSnapshot{}
+	err := instance.GetContext(ctx, ret, "SELECT * FROM contest_standing WHERE contest_id=?", contestId)
+	if err == sql.ErrNoRows {
+		ret, err = nil, nil
+	}
+	if err != nil {
+		panic(err)
+	}
+	return
+}
+
+// UpdStandingSnapshot update if snapshot already exists, otherwise insert
+func UpdStandingSnapshot(ctx context.Context, s StandingSnapshot) {
+	query := `INSERT INTO contest_standing(contest_id, data, create_time)
+VALUES(:contest_id, :data, :create_time)
+ON DUPLICATE KEY UPDATE data=VALUES(data), create_time=VALUES(create_time)`
+	mustNamedExec(ctx, query, s)
+}
+
+func DelStandingSnapshot(ctx context.Context, contestId int) {
+	mustExec(ctx, "DELETE FROM contest_standing WHERE contest_id=?", contestId)
+}
+
+// GetEndedContestsWithoutSnapshot return at most limit contests (without problems)
+// which have ended before end but have no snapshot yet
+func GetEndedContestsWithoutSnapshot(ctx context.Context, end time.Time, limit int) []Contest {
+	query := `SELECT * FROM contest
+WHERE DATE_ADD(start_time, INTERVAL duration MINUTE) < ?
+AND id NOT IN (SELECT contest_id FROM contest_standing)
+ORDER BY start_time DESC LIMIT ?`
+	ret := make([]Contest, 0)
+	mustSelect(ctx, &ret, query, end, limit)
+	return ret
+}
+
+// GetSnapshotContestsByPid return id of contests with snapshot which contain any problem in pids
+func GetSnapshotContestsByPid(ctx context.Context, ojId int, pids []string) []int {
+	ret := make([]int, 0)
+	if len(pids) == 0 {
+		return ret
+	}
+	query, args, err := sqlx.In(`SELECT DISTINCT contest_id FROM contest_problem
+WHERE oj_id = ? AND pid IN (?)
+AND contest_id IN (SELECT contest_id FROM contest_standing)`, ojId, pids)
+	if err != nil {
+		panic(err)
+	}
+	mustSelect(ctx, &ret, instance.Rebind(query), args...)
+	return ret
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// granularity of activity aggregation, weeks are named by the date of their Monday
+const (
+	PeriodDay   = "day"
+	PeriodWeek  = "week"
+	PeriodMonth = "month"
+)
+
+// periodExpr map granularity to sql expression of column $t
+var periodExpr = map[string]string{
+	PeriodDay:   "DATE_FORMAT($t, '%Y-%m-%d')",
+	PeriodWeek:  "DATE_FORMAT(DATE_SUB(DATE($t), INTERVAL WEEKDAY($t) DAY), '%Y-%m-%d')",
+	PeriodMonth: "DATE_FORMAT($t, '%Y-%m')",
+}
+
+// Activity is the number of submissions and newly solved problems in a period
+type Activity struct {
+	Period      string `json:"period" db:"period"`
+	Submissions int    `json:"submissions" db:"submissions"`
+	Solved      int    `json:"solved" db:"solved"`
+}
+
+// GetActivities return activities of the user during [begin, end] by granularity, periods without activity are omitted
+// a problem is counted as solved in the period of its first accepted submission
+func GetActivities(ctx context.Context, username string, granularity string, begin, end time.Time) []Activity {
+	expr := periodExpr[granularity]
+	query := `
+SELECT period, SUM(submissions) AS submissions, SUM(solved) AS solved FROM
+(
+    SELECT ` + strings.ReplaceAll(expr, "$t", "create_time") + ` AS period, COUNT(*) AS submissions, 0 AS solved
+    FROM submission
+    WHERE username = ? AND create_time BETWEEN ? AND ? AND ` + verifiedSubmissionSQL + `
+    GROUP BY period
+    UNION ALL
+    SELECT ` + strings.ReplaceAll(expr, "$t", "solved_time") + ` AS period, 0 AS submissions, COUNT(*) AS solved
+    FROM (
+        SELECT MIN(create_time) AS solved_time FROM submission
+        WHERE is_accepted AND username = ? AND ` + verifiedSubmissionSQL + `
+        GROUP BY oj_id, pid HAVING solved_time BETWEEN ? AND ?
+    ) first_accepted
+    GROUP BY period
+) tmp
+GROUP BY period ORDER BY period`
+	ret := make([]Activity, 0)
+	mustSelect(ctx, &ret, query, username, begin, end, username, begin, end)
+	return ret
+}
+
+// OJStats is the summary of a user on an oj
+// FirstAC is the number of problems whose first submission is accepted
+type OJStats struct {
+	OjId        int `json:"oj_id" db:"oj_id"`
+	Submissions int `json:"submissions" db:"submissions"`
+	Accepted    int `json:"accepted" db:"accepted"`
+	Solved      int `json:"solved" db:"solved"`
+	FirstAC     int `json:"first_ac" db:"first_ac"`
+}
+
+// GetOJStats return summary of the user on each oj with submissions during [begin, end]
+func GetOJStats(ctx context.Context, username string, begin, end time.Time) []OJStats {
+	query := `
+SELECT oj_id,
+       COUNT(*) AS submissions,
+       SUM(is_accepted) AS accepted,
+       COUNT(DISTINCT IF(is_accepted, pid, NULL)) AS solved,
+       SUM(rn = 1 AND is_accepted) AS first_ac
+FROM (
+    SELECT oj_id, pid, is_accepted,
+           ROW_NUMBER() OVER (PARTITION BY oj_id, pid ORDER BY create_time, id) AS rn
+    FROM submission
+    WHERE username = ? AND create_time BETWEEN ? AND ? AND ` + verifiedSubmissionSQL + `
+) ranked
+GROUP BY oj_id ORDER BY oj_id`
+	ret := make([]OJStats, 0)
+	mustSelect(ctx, &ret, query, username, begin, end)
+	return ret
+}
+
+// Streak is consecutive days on each of which the user solved at least one new problem
+type Streak struct {
+	Begin  time.Time `db:"begin_day"`
+	End    time.Time `db:"end_day"`
+	Length int       `db:"length"`
+}
+
+// GetStreaks return all streaks of the user, the latest first
+func GetStreaks(ctx context.Context, username string) []Streak {
+	query := `
+SELECT MIN(day) AS begin_day, MAX(day) AS end_day, COUNT(*) AS length FROM
+(
+    SELECT day, DATE_SUB(day, INTERVAL ROW_NUMBER() OVER (ORDER BY day) DAY) AS grp
+    FROM (
+        SELECT DISTINCT DATE(solved_time) AS day FROM (
+            SELECT MIN(create_time) AS solved_time FROM submission
+            WHERE is_accepted AND username = ? AND ` + verifiedSubmissionSQL + `
+            GROUP BY oj_id, pid
+        ) first_accepted
+    ) days
+) tmp
+GROUP BY grp ORDER BY end_day DESC`
+	ret := make([]Streak, 0)
+	mustSelect(ctx, &ret, query, username)
+	return ret
+}
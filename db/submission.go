@@ -5,6 +5,8 @@ import (
 	"sort"
 	"time"
 
+	"github.com/jmoiron/sqlx"
+
 	"zuccacm-server/utils"
 )
 
@@ -13,9 +15,15 @@ type Submission struct {
 	Username    string   `json:"username" db:"username"`
 	OjId        int      `json:"oj_id" db:"oj_id"`
 	AccountOjId int      `json:"account_oj_id" db:"account_oj_id"`
+	Account     string   `json:"account" db:"account"`
 	Sid         string   `json:"sid" db:"sid"`
 	Pid         string   `json:"pid" db:"pid"`
 	IsAccepted  bool     `json:"is_accepted" db:"is_accepted"`
+	Score       *float64 `json:"score,omitempty" db:"score"`
+	Verdict     string   `json:"verdict" db:"verdict"`
+	Language    string   `json:"language" db:"language"`
+	RunTime     int      `json:"run_time" db:"run_time"`
+	Memory      int      `json:"memory" db:"memory"`
 	CreateTime  Datetime `json:"create_time" db:"create_time"`
 }
 
@@ -24,9 +32,15 @@ type dbSubmission struct {
 	Username    string    `json:"username" db:"username"`
 	OjId        int       `json:"oj_id" db:"oj_id"`
 	AccountOjId int       `json:"account_oj_id" db:"account_oj_id"`
+	Account     string    `json:"account" db:"account"`
 	Sid         string    `json:"sid" db:"sid"`
 	Pid         string    `json:"pid" db:"pid"`
 	IsAccepted  bool      `json:"is_accepted" db:"is_accepted"`
+	Score       *float64  `json:"score,omitempty" db:"score"`
+	Verdict     string    `json:"verdict" db:"verdict"`
+	Language    string    `json:"language" db:"language"`
+	RunTime     int       `json:"run_time" db:"run_time"`
+	Memory      int       `json:"memory" db:"memory"`
 	CreateTime  time.Time `json:"create_time" db:"create_time"`
 }
 
@@ -36,9 +50,15 @@ func (s *Submission) dbType() *dbSubmission {
 		Username:    s.Username,
 		OjId:        s.OjId,
 		AccountOjId: s.AccountOjId,
+		Account:     s.Account,
 		Sid:         s.Sid,
 		Pid:         s.Pid,
 		IsAccepted:  s.IsAccepted,
+		Score:       s.Score,
+		Verdict:     s.Verdict,
+		Language:    s.Language,
+		RunTime:     s.RunTime,
+		Memory:      s.Memory,
 		CreateTime:  time.Time(s.CreateTime),
 	}
 }
@@ -49,14 +69,22 @@ func (s *dbSubmission) jsonType() *Submission {
 		Username:    s.Username,
 		OjId:        s.OjId,
 		AccountOjId: s.AccountOjId,
+		Account:     s.Account,
 		Sid:         s.Sid,
 		Pid:         s.Pid,
 		IsAccepted:  s.IsAccepted,
+		Score:       s.Score,
+		Verdict:     s.Verdict,
+		Language:    s.Language,
+		RunTime:     s.RunTime,
+		Memory:      s.Memory,
 		CreateTime:  Datetime(s.CreateTime),
 	}
 }
 
-func AddSubmission(ctx context.Context, s []Submission) {
+// AddSubmission return the number of submissions which are really inserted
+// submissions of unverified accounts are dropped, they are crawled again once verified
+func AddSubmission(ctx context.Context, s []Submission) (inserted int64) {
 	accounts := GetAllAccounts(ctx)
 	type key struct {
 		oj      int
@@ -64,7 +92,9 @@ func AddSubmission(ctx context.Context, s []Submission) {
 	}
 	mp := make(map[key]string)
 	for _, account := range accounts {
-		mp[key{account.OjId, account.Account}] = account.Username
+		if account.IsVerified {
+			mp[key{account.OjId, account.Account}] = account.Username
+		}
 	}
 	data := make([]dbSubmission, 0)
 	for _, si := range s {
@@ -72,28 +102,35 @@ func AddSubmission(ctx context.Context, s []Submission) {
 		if _, ok := mp[k]; !ok {
 			continue
 		}
-		si.Username = mp[k]
+		si.Account, si.Username = si.Username, mp[k]
 		data = append(data, *si.dbType())
 	}
-	query := `INSERT IGNORE INTO submission(username, oj_id, account_oj_id, sid, pid, is_accepted, create_time)
-VALUES(:username, :oj_id, :account_oj_id, :sid, :pid, :is_accepted, :create_time)`
+	query := `INSERT IGNORE INTO submission(username, oj_id, account_oj_id, account, sid, pid, is_accepted, score, verdict, language, run_time, memory, create_time)
+VALUES(:username, :oj_id, :account_oj_id, :account, :sid, :pid, :is_accepted, :score, :verdict, :language, :run_time, :memory, :create_time)`
 	tx := instance.MustBeginTx(ctx, nil)
 	n := len(data)
 	groupSize := 5000
 	for i := 0; i < n; i += groupSize {
-		mustNamedExecTx(tx, ctx, query, data[i:utils.Min(i+groupSize, n)])
+		ret := mustNamedExecTx(tx, ctx, query, data[i:utils.Min(i+groupSize, n)])
+		cnt, err := ret.RowsAffected()
+		if err != nil {
+			panic(err)
+		}
+		inserted += cnt
 	}
 	mustCommit(tx)
+	return
 }
 
 // GetSubmissionsInContest return submissions from team_user in this contest
 func GetSubmissionsInContest(ctx context.Context, contestId int) []Submission {
 	query := `
-SELECT submission.username AS username, is_accepted, create_time, submission.oj_id AS oj_id, submission.pid AS pid
+SELECT submission.id AS id, submission.username AS username, is_accepted, score, verdict, language, create_time, submission.oj_id AS oj_id, submission.pid AS pid
 FROM submission, contest_problem
 WHERE submission.oj_id = contest_problem.oj_id
   AND submission.pid = contest_problem.pid
   AND contest_problem.contest_id = ?
+  AND ` + verifiedSubmissionSQL + `
   AND username IN (SELECT DISTINCT username
                    FROM team_user_rel,
                         contest_team_rel
@@ -105,6 +142,28 @@ ORDER BY create_time`
 	return ret
 }
 
+// GetVirtualSubmissionsInContest return submissions from virtual participants of this contest
+// participants who are also in team_user are excluded, see GetSubmissionsInContest
+func GetVirtualSubmissionsInContest(ctx context.Context, contestId int) []Submission {
+	query := `
+SELECT submission.username AS username, is_accepted, score, verdict, language, create_time, submission.oj_id AS oj_id, submission.pid AS pid
+FROM submission, contest_problem
+WHERE submission.oj_id = contest_problem.oj_id
+  AND submission.pid = contest_problem.pid
+  AND contest_problem.contest_id = ?
+  AND ` + verifiedSubmissionSQL + `
+  AND username IN (SELECT username FROM contest_virtual WHERE contest_id = ?)
+  AND username NOT IN (SELECT DISTINCT username
+                       FROM team_user_rel,
+                            contest_team_rel
+                       WHERE team_user_rel.team_id = contest_team_rel.team_id
+                         AND contest_id = ?)
+ORDER BY create_time`
+	ret := make([]Submission, 0)
+	mustSelect(ctx, &ret, query, contestId, contestId, contestId)
+	return ret
+}
+
 func GetAcceptedSubmissionByUsername(ctx context.Context, username string, begin, end time.Time) []Submission {
 	//query := `SELECT min(create_time) AS create_time
 	//FROM submission WHERE is_accepted AND username=?
@@ -121,7 +180,7 @@ func GetAcceptedSubmissionByUsername(ctx context.Context, username string, begin
 	//ON s.oj_id = sub.oj_id AND s.pid = sub.pid AND s.create_time = sub.min_create_time
 	//WHERE s.is_accepted AND s.username=?;
 	//`
-	query := `SELECT id,username,oj_id,account_oj_id,sid,pid,is_accepted,create_time 
+	query := `SELECT id,username,oj_id,account_oj_id,sid,pid,is_accepted,score,create_time 
 FROM (
     SELECT 
         *,
@@ -156,6 +215,7 @@ type overview struct {
 	Users     []overviewCell `json:"users"`
 }
 
+// GetOverview count submissions of official users, submissions of unverified accounts are excluded
 func GetOverview(ctx context.Context, begin, end time.Time) []overview {
 	ret := make([]overview, 0)
 	var groups []TeamGroup
@@ -188,12 +248,14 @@ SELECT username, nickname, group_id,
     (
          SELECT MIN(create_time) FROM submission
          WHERE is_accepted AND username = official_user.username
+         AND ` + verifiedSubmissionSQL + `
          GROUP BY oj_id, pid HAVING MIN(create_time) BETWEEN ? AND ?
     ) tmp
 ) solved,
 (
     SELECT COUNT(*) FROM submission
     WHERE username = official_user.username AND create_time BETWEEN ? AND ?
+    AND ` + verifiedSubmissionSQL + `
 ) submission
 FROM official_user
 WHERE is_enable`
@@ -229,3 +291,17 @@ WHERE is_enable`
 	})
 	return ret
 }
+
+// GetExistingSids return sids of submissions which have been added in the oj
+func GetExistingSids(ctx context.Context, ojId int, sids []string) []string {
+	ret := make([]string, 0)
+	if len(sids) == 0 {
+		return ret
+	}
+	query, args, err := sqlx.In("SELECT sid FROM submission WHERE oj_id = ? AND sid IN (?)", ojId, sids)
+	if err != nil {
+		panic(err)
+	}
+	mustSelect(ctx, &ret, instance.Rebind(query), args...)
+	return ret
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	log "github.com/sirupsen/logrus"
 	"sort"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
 )
 
 type Team struct {
@@ -227,3 +229,82 @@ func UpdTeamEnable(ctx context.Context, team Team) {
 	mustNamedExec(ctx, updTeamEnableSQL, team)
 	mustCommit(tx)
 }
+
+type TeamGroupInContest struct {
+	GroupId   int    `json:"group_id" db:"group_id"`
+	GroupName string `json:"group_name" db:"group_name"`
+	IsGrade   bool   `json:"is_grade" db:"is_grade"`
+	TeamId    int    `json:"team_id" db:"team_id"`
+}
+
+// GetTeamGroupsInContest return groups of teams in this contest, grade groups come first
+func GetTeamGroupsInContest(ctx context.Context, contestId int) []TeamGroupInContest {
+	query := `SELECT team_group.group_id AS group_id, group_name, is_grade, contest_team_rel.team_id AS team_id
+FROM team_group, team_group_rel, contest_team_rel
+WHERE team_group.group_id = team_group_rel.group_id
+AND team_group_rel.team_id = contest_team_rel.team_id
+AND contest_id = ?
+ORDER BY is_grade DESC, group_id`
+	ret := make([]TeamGroupInContest, 0)
+	mustSelect(ctx, &ret, query, contestId)
+	return ret
+}
+
+// GetTeammates return users in the same (not self) teams with the user
+func GetTeammates(ctx context.Context, username string) []string {
+	query := `SELECT DISTINCT b.username
+FROM team_user_rel AS a, team_user_rel AS b, team
+WHERE a.team_id = b.team_id AND a.team_id = team.id AND NOT team.is_self
+AND a.username = ? AND b.username != ?`
+	ret := make([]string, 0)
+	mustSelect(ctx, &ret, query, username, username)
+	return ret
+}
+
+// GetTeamUsernames return usernames of members of the team
+func GetTeamUsernames(ctx context.Context, teamId int) []string {
+	ret := make([]string, 0)
+	mustSelect(ctx, &ret, "SELECT username FROM team_user_rel WHERE team_id=? ORDER BY username", teamId)
+	return ret
+}
+
+// UpdTeamUsers replace members of the team, return users added to and removed from the team
+func UpdTeamUsers(ctx context.Context, teamId int, usernames []string) (added, removed []string) {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	var old []string
+	if err := tx.SelectContext(ctx, &old, "SELECT username FROM team_user_rel WHERE team_id=? FOR UPDATE", teamId); err != nil {
+		panic(err)
+	}
+	keep := make(map[string]bool)
+	for _, u := range usernames {
+		keep[u] = true
+	}
+	had := make(map[string]bool)
+	for _, u := range old {
+		had[u] = true
+		if !keep[u] {
+			removed = append(removed, u)
+		}
+	}
+	users := make([]TeamUser, 0)
+	for _, u := range usernames {
+		if !had[u] {
+			had[u] = true
+			added = append(added, u)
+			users = append(users, TeamUser{teamId, u})
+		}
+	}
+	if len(removed) > 0 {
+		query, args, err := sqlx.In("DELETE FROM team_user_rel WHERE team_id=? AND username IN (?)", teamId, removed)
+		if err != nil {
+			panic(err)
+		}
+		mustExecTx(tx, ctx, tx.Rebind(query), args...)
+	}
+	if len(users) > 0 {
+		mustNamedExecTx(tx, ctx, addTeamUserRelSQL, users)
+	}
+	mustCommit(tx)
+	return
+}
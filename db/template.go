@@ -0,0 +1,125 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+)
+
+// ContestTemplate is the default settings of new contests, such as weekly training
+type ContestTemplate struct {
+	Id          int    `json:"id" db:"id"`
+	Name        string `json:"name" db:"name"`
+	OjId        int    `json:"oj_id" db:"oj_id"`
+	Duration    int    `json:"duration" db:"duration"`
+	ScoringMode string `json:"scoring_mode" db:"scoring_mode"`
+	Groups      []int  `json:"groups"`
+	Teams       []int  `json:"teams"`
+}
+
+type dbContestTemplate struct {
+	Id          int    `db:"id"`
+	Name        string `db:"name"`
+	OjId        int    `db:"oj_id"`
+	Duration    int    `db:"duration"`
+	ScoringMode string `db:"scoring_mode"`
+	Groups      string `db:"group_ids"`
+	Teams       string `db:"team_ids"`
+}
+
+func (t *ContestTemplate) dbType() *dbContestTemplate {
+	groups, err := json.Marshal(t.Groups)
+	if err != nil {
+		panic(err)
+	}
+	teams, err := json.Marshal(t.Teams)
+	if err != nil {
+		panic(err)
+	}
+	return &dbContestTemplate{
+		Id:          t.Id,
+		Name:        t.Name,
+		OjId:        t.OjId,
+		Duration:    t.Duration,
+		ScoringMode: t.ScoringMode,
+		Groups:      string(groups),
+		Teams:       string(teams),
+	}
+}
+
+func (t *dbContestTemplate) jsonType() *ContestTemplate {
+	ret := &ContestTemplate{
+		Id:          t.Id,
+		Name:        t.Name,
+		OjId:        t.OjId,
+		Duration:    t.Duration,
+		ScoringMode: t.ScoringMode,
+		Groups:      make([]int, 0),
+		Teams:       make([]int, 0),
+	}
+	if err := json.Unmarshal([]byte(t.Groups), &ret.Groups); err != nil {
+		panic(err)
+	}
+	if err := json.Unmarshal([]byte(t.Teams), &ret.Teams); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Apply fill the empty fields of contest with the template
+func (t *ContestTemplate) Apply(c *Contest) {
+	if c.OjId == 0 {
+		c.OjId = t.OjId
+	}
+	if c.Duration == 0 {
+		c.Duration = t.Duration
+	}
+	if c.ScoringMode == "" {
+		c.ScoringMode = t.ScoringMode
+	}
+	if len(c.Groups) == 0 {
+		c.Groups = t.Groups
+	}
+	if len(c.Teams) == 0 {
+		c.Teams = t.Teams
+	}
+}
+
+func GetContestTemplates(ctx context.Context) []ContestTemplate {
+	var data []dbContestTemplate
+	mustSelect(ctx, &data, "SELECT * FROM contest_template ORDER BY id")
+	ret := make([]ContestTemplate, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+func GetContestTemplateById(ctx context.Context, id int) ContestTemplate {
+	var t dbContestTemplate
+	mustGet(ctx, &t, "SELECT * FROM contest_template WHERE id=?", id)
+	return *t.jsonType()
+}
+
+// AddContestTemplate return the new ContestTemplate with ContestTemplate.Id
+func AddContestTemplate(ctx context.Context, t ContestTemplate) ContestTemplate {
+	query := `INSERT INTO contest_template(name, oj_id, duration, scoring_mode, group_ids, team_ids)
+VALUES(:name, :oj_id, :duration, :scoring_mode, :group_ids, :team_ids)`
+	res := mustNamedExec(ctx, query, t.dbType())
+	id, err := res.LastInsertId()
+	if err != nil {
+		panic(err)
+	}
+	t.Id = int(id)
+	return t
+}
+
+func UpdContestTemplate(ctx context.Context, t ContestTemplate) {
+	query := `UPDATE contest_template
+SET name=:name, oj_id=:oj_id, duration=:duration, scoring_mode=:scoring_mode, group_ids=:group_ids, team_ids=:team_ids
+WHERE id=:id`
+	mustNamedExec(ctx, query, t.dbType())
+}
+
+func DelContestTemplate(ctx context.Context, id int) {
+	mustExec(ctx, "DELETE FROM contest_template WHERE id=?", id)
+}
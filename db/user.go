@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"sort"
 
+	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 
 	"zuccacm-server/enum/errorx"
@@ -24,6 +25,7 @@ type User struct {
 	Phone    string `db:"phone" json:"phone"`
 	QQ       string `db:"qq" json:"qq"`
 	TShirt   string `db:"t_shirt" json:"t_shirt"`
+	Email    string `db:"email" json:"email"`
 }
 
 // MustGetUser panic err when user not found
@@ -141,13 +143,13 @@ func AddUser(ctx context.Context, user User) {
 	mustCommit(tx)
 }
 
-// UpdUser update User basic info (nickname, id_card, phone, qq, t_shirt)
+// UpdUser update User basic info (nickname, id_card, phone, qq, t_shirt, email)
 // self-team will update Team.Name at the same time
 func UpdUser(ctx context.Context, user User) {
 	tx := instance.MustBeginTx(ctx, nil)
 	defer tx.Rollback()
 	query := `UPDATE user
-SET nickname=:nickname, id_card=:id_card, phone=:phone, qq=:qq, t_shirt=:t_shirt
+SET nickname=:nickname, id_card=:id_card, phone=:phone, qq=:qq, t_shirt=:t_shirt, email=:email
 WHERE username=:username`
 	mustNamedExecTx(tx, ctx, query, user)
 	query = "UPDATE team SET name=:name WHERE id=:id"
@@ -308,3 +310,17 @@ AND team_group.group_id = team_group_rel.group_id AND is_grade`
 	})
 	return ret
 }
+
+// GetUserEmails return non-empty emails of enabled users in usernames
+func GetUserEmails(ctx context.Context, usernames []string) []string {
+	ret := make([]string, 0)
+	if len(usernames) == 0 {
+		return ret
+	}
+	query, args, err := sqlx.In("SELECT email FROM user WHERE is_enable AND email != '' AND username IN (?)", usernames)
+	if err != nil {
+		panic(err)
+	}
+	mustSelect(ctx, &ret, instance.Rebind(query), args...)
+	return ret
+}
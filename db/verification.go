@@ -0,0 +1,109 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"zuccacm-server/enum/errorx"
+)
+
+// methods of account challenge
+const (
+	ChallengeProfile      = "profile"       // token in organization or first name of the profile
+	ChallengeCompileError = "compile_error" // submit a compile error to pid
+)
+
+// unverifiedAccountsSQL select (username, oj_id, account) of bindings which are not verified
+const unverifiedAccountsSQL = "SELECT username, oj_id, account FROM oj_user_rel WHERE NOT is_verified"
+
+// verifiedSubmissionSQL is the condition of submissions which count in official results
+// it is checked per binding, submissions of previous accounts are kept while the current one is unverified
+const verifiedSubmissionSQL = "(submission.username, submission.account_oj_id, submission.account) NOT IN (" + unverifiedAccountsSQL + ")"
+
+type AccountChallenge struct {
+	Username   string   `json:"username" db:"username"`
+	OjId       int      `json:"oj_id" db:"oj_id"`
+	Account    string   `json:"account" db:"account"`
+	Method     string   `json:"method" db:"method"`
+	Token      string   `json:"token" db:"token"`
+	Pid        string   `json:"pid" db:"pid"`
+	CreateTime Datetime `json:"create_time" db:"create_time"`
+}
+
+type dbAccountChallenge struct {
+	Username   string    `db:"username"`
+	OjId       int       `db:"oj_id"`
+	Account    string    `db:"account"`
+	Method     string    `db:"method"`
+	Token      string    `db:"token"`
+	Pid        string    `db:"pid"`
+	CreateTime time.Time `db:"create_time"`
+}
+
+func (c *AccountChallenge) dbType() *dbAccountChallenge {
+	return &dbAccountChallenge{
+		Username:   c.Username,
+		OjId:       c.OjId,
+		Account:    c.Account,
+		Method:     c.Method,
+		Token:      c.Token,
+		Pid:        c.Pid,
+		CreateTime: time.Time(c.CreateTime),
+	}
+}
+
+func (c *dbAccountChallenge) jsonType() *AccountChallenge {
+	return &AccountChallenge{
+		Username:   c.Username,
+		OjId:       c.OjId,
+		Account:    c.Account,
+		Method:     c.Method,
+		Token:      c.Token,
+		Pid:        c.Pid,
+		CreateTime: Datetime(c.CreateTime),
+	}
+}
+
+// SetAccountChallenge replace the pending challenge of the user on the oj
+func SetAccountChallenge(ctx context.Context, c AccountChallenge) {
+	query := `REPLACE INTO account_challenge(username, oj_id, account, method, token, pid, create_time)
+VALUES(:username, :oj_id, :account, :method, :token, :pid, :create_time)`
+	mustNamedExec(ctx, query, c.dbType())
+}
+
+// GetAccountChallenge panic ErrNotFound if there is no pending challenge
+func GetAccountChallenge(ctx context.Context, username string, ojId int) AccountChallenge {
+	var c dbAccountChallenge
+	err := instance.GetContext(ctx, &c, "SELECT * FROM account_challenge WHERE username=? AND oj_id=?", username, ojId)
+	if err == sql.ErrNoRows {
+		panic(errorx.ErrNotFound.WithMessage("challenge not found, please create it first"))
+	}
+	if err != nil {
+		panic(err)
+	}
+	return *c.jsonType()
+}
+
+// GetAccountChallengeByToken return the challenge of the account with token, ok is false if not found
+func GetAccountChallengeByToken(ctx context.Context, ojId int, account, token string) (AccountChallenge, bool) {
+	var c dbAccountChallenge
+	query := "SELECT * FROM account_challenge WHERE oj_id=? AND account=? AND token=?"
+	err := instance.GetContext(ctx, &c, query, ojId, account, token)
+	if err == sql.ErrNoRows {
+		return AccountChallenge{}, false
+	}
+	if err != nil {
+		panic(err)
+	}
+	return *c.jsonType(), true
+}
+
+// UpdAccountVerified set is_verified of the current account and clear the pending challenge
+func UpdAccountVerified(ctx context.Context, username string, ojId int, isVerified bool) {
+	tx := instance.MustBeginTx(ctx, nil)
+	defer tx.Rollback()
+	mustExecTx(tx, ctx, "UPDATE oj_user_rel SET is_verified=? WHERE username=? AND oj_id=?", isVerified, username, ojId)
+	mustExecTx(tx, ctx, "DELETE FROM account_challenge WHERE username=? AND oj_id=?", username, ojId)
+	mustCommit(tx)
+}
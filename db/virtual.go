@@ -0,0 +1,83 @@
+package db
+
+import (
+	"context"
+	"time"
+)
+
+// VirtualParticipation is a user taking part in a finished contest at another time
+type VirtualParticipation struct {
+	ContestId int      `json:"contest_id" db:"contest_id"`
+	Username  string   `json:"username" db:"username"`
+	Nickname  string   `json:"nickname" db:"nickname"`
+	StartTime Datetime `json:"start_time" db:"start_time"`
+}
+
+// UpdVirtual update if virtual participation already exists, otherwise insert
+func UpdVirtual(ctx context.Context, v VirtualParticipation) {
+	query := `INSERT INTO contest_virtual(contest_id, username, start_time)
+VALUES(?, ?, ?) ON DUPLICATE KEY UPDATE start_time=VALUES(start_time)`
+	mustExec(ctx, query, v.ContestId, v.Username, time.Time(v.StartTime))
+}
+
+func DelVirtual(ctx context.Context, contestId int, username string) {
+	mustExec(ctx, "DELETE FROM contest_virtual WHERE contest_id=? AND username=?", contestId, username)
+}
+
+// GetVirtualsByContest return virtual participations of the contest, including users in teams of the contest
+func GetVirtualsByContest(ctx context.Context, contestId int) []VirtualParticipation {
+	query := `SELECT contest_id, user.username AS username, nickname, start_time
+FROM contest_virtual, user
+WHERE contest_virtual.username = user.username AND contest_id = ?`
+	ret := make([]VirtualParticipation, 0)
+	mustSelect(ctx, &ret, query, contestId)
+	return ret
+}
+
+// GetVirtualsByUser return map from contest id to virtual start time of a user
+func GetVirtualsByUser(ctx context.Context, username string) map[int]Datetime {
+	query := `SELECT contest_id, username, start_time FROM contest_virtual WHERE username = ?`
+	var data []VirtualParticipation
+	mustSelect(ctx, &data, query, username)
+	ret := make(map[int]Datetime)
+	for _, x := range data {
+		ret[x.ContestId] = x.StartTime
+	}
+	return ret
+}
+
+// IsUserInContest return whether the user is in any team of the contest
+func IsUserInContest(ctx context.Context, contestId int, username string) bool {
+	query := `SELECT EXISTS(
+    SELECT * FROM team_user_rel, contest_team_rel
+    WHERE team_user_rel.team_id = contest_team_rel.team_id AND contest_id = ? AND username = ?
+)`
+	var ret bool
+	mustGet(ctx, &ret, query, contestId, username)
+	return ret
+}
+
+// GetVirtualContestsByUser return contests (with problems) which the user takes part in virtually
+// but is not in any team of, the virtual start time is during [begin, end]
+// If groupId=0 then return contests in any groups (or no group)
+func GetVirtualContestsByUser(ctx context.Context, username string, begin, end time.Time, groupId int) []Contest {
+	query := `SELECT contest_id FROM contest_virtual
+WHERE username = ? AND start_time BETWEEN ? AND ?
+AND contest_id NOT IN (
+    SELECT contest_id FROM team_user_rel, contest_team_rel
+    WHERE team_user_rel.team_id = contest_team_rel.team_id AND username = ?
+)`
+	args := []interface{}{username, begin, end, username}
+	if groupId != 0 {
+		query += " AND contest_id IN (SELECT contest_id FROM contest_group_rel WHERE group_id = ?)"
+		args = append(args, groupId)
+	}
+	query += " ORDER BY start_time DESC"
+	ids := make([]int, 0)
+	mustSelect(ctx, &ids, query, args...)
+	ret := make([]Contest, 0)
+	for _, id := range ids {
+		ret = append(ret, GetContestById(ctx, id))
+	}
+	return ret
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Webhook is a subscription of domain events, payloads are signed by Secret
+// Events is the filter of event types, empty means all events
+type Webhook struct {
+	Id       int      `json:"id" db:"id"`
+	URL      string   `json:"url" db:"url"`
+	Secret   string   `json:"secret" db:"secret"`
+	Events   []string `json:"events"`
+	IsEnable bool     `json:"is_enable" db:"is_enable"`
+}
+
+type dbWebhook struct {
+	Id       int    `db:"id"`
+	URL      string `db:"url"`
+	Secret   string `db:"secret"`
+	Events   string `db:"events"`
+	IsEnable bool   `db:"is_enable"`
+}
+
+func (h *Webhook) dbType() *dbWebhook {
+	events, err := json.Marshal(h.Events)
+	if err != nil {
+		panic(err)
+	}
+	return &dbWebhook{
+		Id:       h.Id,
+		URL:      h.URL,
+		Secret:   h.Secret,
+		Events:   string(events),
+		IsEnable: h.IsEnable,
+	}
+}
+
+func (h *dbWebhook) jsonType() *Webhook {
+	ret := &Webhook{
+		Id:       h.Id,
+		URL:      h.URL,
+		Secret:   h.Secret,
+		Events:   make([]string, 0),
+		IsEnable: h.IsEnable,
+	}
+	if err := json.Unmarshal([]byte(h.Events), &ret.Events); err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// Match return whether the webhook subscribes the event
+func (h *Webhook) Match(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event || e == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is the log of one attempt to deliver an event
+type WebhookDelivery struct {
+	Id         int      `json:"id" db:"id"`
+	WebhookId  int      `json:"webhook_id" db:"webhook_id"`
+	DeliveryId string   `json:"delivery_id" db:"delivery_id"`
+	Event      string   `json:"event" db:"event"`
+	Payload    string   `json:"payload" db:"payload"`
+	Attempt    int      `json:"attempt" db:"attempt"`
+	StatusCode int      `json:"status_code" db:"status_code"`
+	Error      string   `json:"error" db:"error"`
+	IsSuccess  bool     `json:"is_success" db:"is_success"`
+	CreateTime Datetime `json:"create_time" db:"create_time"`
+}
+
+// GetWebhooks return all webhooks if isEnable=false
+func GetWebhooks(ctx context.Context, isEnable bool) []Webhook {
+	query := "SELECT * FROM webhook"
+	if isEnable {
+		query += " WHERE is_enable"
+	}
+	var data []dbWebhook
+	mustSelect(ctx, &data, query)
+	ret := make([]Webhook, 0)
+	for _, x := range data {
+		ret = append(ret, *x.jsonType())
+	}
+	return ret
+}
+
+func AddWebhook(ctx context.Context, h Webhook) {
+	query := "INSERT INTO webhook(url, secret, events, is_enable) VALUES(:url, :secret, :events, :is_enable)"
+	mustNamedExec(ctx, query, h.dbType())
+}
+
+func UpdWebhook(ctx context.Context, h Webhook) {
+	query := "UPDATE webhook SET url=:url, secret=:secret, events=:events, is_enable=:is_enable WHERE id=:id"
+	mustNamedExec(ctx, query, h.dbType())
+}
+
+func DelWebhook(ctx context.Context, id int) {
+	mustExec(ctx, "DELETE FROM webhook WHERE id=?", id)
+}
+
+func AddWebhookDelivery(ctx context.Context, d WebhookDelivery) {
+	query := `INSERT INTO webhook_delivery(webhook_id, delivery_id, event, payload, attempt, status_code, error, is_success, create_time)
+VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	mustExec(ctx, query, d.WebhookId, d.DeliveryId, d.Event, d.Payload, d.Attempt,
+		d.StatusCode, d.Error, d.IsSuccess, time.Time(d.CreateTime))
+}
+
+// GetWebhookDeliveries return delivery log of the webhook, latest first
+func GetWebhookDeliveries(ctx context.Context, webhookId int, page Page) []WebhookDelivery {
+	query := "SELECT * FROM webhook_delivery WHERE webhook_id = ? ORDER BY id DESC"
+	ret := make([]WebhookDelivery, 0)
+	mustSelect(ctx, &ret, page.query(query), webhookId)
+	return ret
+}
@@ -66,3 +66,16 @@ func AddXcpcTeamRel(ctx context.Context, xcpc_team_rel XcpcTeamRel) {
 	}
 	mustCommit(tx)
 }
+
+// UpdXcpcTeamAward set medal and award of the team in xcpc, return whether they are changed
+func UpdXcpcTeamAward(ctx context.Context, xcpc_team_rel XcpcTeamRel) (changed bool) {
+	query := `UPDATE xcpc_team_rel SET medal=?, award=?
+WHERE xcpc_id=? AND team_id=? AND (medal<>? OR award<>?)`
+	res := instance.MustExecContext(ctx, query, xcpc_team_rel.Medal, xcpc_team_rel.Award,
+		xcpc_team_rel.XcpcId, xcpc_team_rel.TeamId, xcpc_team_rel.Medal, xcpc_team_rel.Award)
+	n, err := res.RowsAffected()
+	if err != nil {
+		panic(err)
+	}
+	return n > 0
+}
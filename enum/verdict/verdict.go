@@ -0,0 +1,68 @@
+package verdict
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	OK  = "OK"  // accepted
+	WA  = "WA"  // wrong answer
+	TLE = "TLE" // time limit exceeded
+	MLE = "MLE" // memory limit exceeded
+	OLE = "OLE" // output limit exceeded
+	RE  = "RE"  // runtime error
+	PE  = "PE"  // presentation error
+	CE  = "CE"  // compilation error
+	OT  = "OT"  // other, such as skipped, hacked or judgement failed
+)
+
+// aliases of verdicts used by OJs (in upper case)
+var toVerdict = map[string]string{
+	"OK":                        OK,
+	"AC":                        OK,
+	"ACCEPTED":                  OK,
+	"WA":                        WA,
+	"WRONG_ANSWER":              WA,
+	"WRONG ANSWER":              WA,
+	"TLE":                       TLE,
+	"TIME_LIMIT_EXCEEDED":       TLE,
+	"TIME LIMIT EXCEEDED":       TLE,
+	"IDLENESS_LIMIT_EXCEEDED":   TLE,
+	"MLE":                       MLE,
+	"MEMORY_LIMIT_EXCEEDED":     MLE,
+	"MEMORY LIMIT EXCEEDED":     MLE,
+	"OLE":                       OLE,
+	"OUTPUT_LIMIT_EXCEEDED":     OLE,
+	"OUTPUT LIMIT EXCEEDED":     OLE,
+	"RE":                        RE,
+	"RTE":                       RE,
+	"RUNTIME_ERROR":             RE,
+	"RUNTIME ERROR":             RE,
+	"PE":                        PE,
+	"PRESENTATION_ERROR":        PE,
+	"PRESENTATION ERROR":        PE,
+	"CE":                        CE,
+	"COMPILATION_ERROR":         CE,
+	"COMPILATION ERROR":         CE,
+	"COMPILE ERROR":             CE,
+	"OT":                        OT,
+	"SKIPPED":                   OT,
+	"CHALLENGED":                OT,
+	"FAILED":                    OT,
+	"REJECTED":                  OT,
+	"PARTIAL":                   OT,
+	"SECURITY_VIOLATED":         OT,
+	"CRASHED":                   OT,
+	"INPUT_PREPARATION_CRASHED": OT,
+}
+
+// Parse return the verdict of s, which is case-insensitive
+func Parse(s string) (ret string, err error) {
+	ret, ok := toVerdict[strings.ToUpper(strings.TrimSpace(s))]
+	if !ok {
+		err = errors.New(fmt.Sprintf("parse verdict error, not supported verdict: %s", s))
+	}
+	return
+}
@@ -1 +1,113 @@
 package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/utils"
+)
+
+// heatmapDays is the length of heatmap when the interval is not given
+const heatmapDays = 365
+
+// maxHeatmapDays is the max length of heatmap, only the last days of a longer interval are kept
+const maxHeatmapDays = 5 * 366
+
+func init() {
+	userRouter.HandleFunc("/{username}/stats", getUserStats).Methods("GET")
+}
+
+type ojStats struct {
+	db.OJStats
+	OjName string `json:"oj_name"`
+}
+
+// heatmap is newly solved problems and submissions of each day since Begin
+type heatmap struct {
+	Begin       string `json:"begin"`
+	Solved      []int  `json:"solved"`
+	Submissions []int  `json:"submissions"`
+}
+
+func today() time.Time {
+	y, m, d := time.Now().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.Local)
+}
+
+// getUserStats return streaks, totals, active days, heatmap and weekly/monthly activities of the user
+// streaks are of all time, others are during [begin_time, end_time] (heatmap is of the last year by default, and of at most maxHeatmapDays)
+// a streak is current if it ends today or yesterday
+func getUserStats(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	begin, end := getParamDateInterval(r)
+	if end.Before(begin) {
+		panic(errorx.ErrBadRequest.WithMessage("end_time can't be before begin_time"))
+	}
+	ctx := r.Context()
+	db.MustGetUser(ctx, username)
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+
+	data := struct {
+		CurrentStreak int           `json:"current_streak"`
+		LongestStreak int           `json:"longest_streak"`
+		ActiveDays    int           `json:"active_days"`
+		Submissions   int           `json:"submissions"`
+		Solved        int           `json:"solved"`
+		FirstAC       int           `json:"first_ac"`
+		OJs           []ojStats     `json:"ojs"`
+		Heatmap       heatmap       `json:"heatmap"`
+		Weekly        []db.Activity `json:"weekly"`
+		Monthly       []db.Activity `json:"monthly"`
+	}{
+		OJs:     make([]ojStats, 0),
+		Weekly:  db.GetActivities(ctx, username, db.PeriodWeek, begin, end),
+		Monthly: db.GetActivities(ctx, username, db.PeriodMonth, begin, end),
+	}
+
+	streaks := db.GetStreaks(ctx, username)
+	if len(streaks) > 0 && utils.SubDays(streaks[0].End, today()) <= 1 {
+		data.CurrentStreak = streaks[0].Length
+	}
+	for _, s := range streaks {
+		data.LongestStreak = utils.Max(data.LongestStreak, s.Length)
+	}
+
+	for _, x := range db.GetOJStats(ctx, username, begin, end) {
+		data.OJs = append(data.OJs, ojStats{x, oj[x.OjId]})
+		data.Submissions += x.Submissions
+		data.Solved += x.Solved
+		data.FirstAC += x.FirstAC
+	}
+
+	heatBegin, heatEnd := begin, end
+	if begin == defaultBeginTime {
+		heatEnd = today().AddDate(0, 0, 1).Add(-time.Second)
+		heatBegin = today().AddDate(0, 0, 1-heatmapDays)
+	}
+	if utils.SubDays(heatBegin, heatEnd) >= maxHeatmapDays {
+		y, m, d := heatEnd.Date()
+		heatBegin = time.Date(y, m, d, 0, 0, 0, 0, time.Local).AddDate(0, 0, 1-maxHeatmapDays)
+	}
+	n := utils.SubDays(heatBegin, heatEnd) + 1
+	data.Heatmap = heatmap{
+		Begin:       db.Datetime(heatBegin).Date(),
+		Solved:      make([]int, n),
+		Submissions: make([]int, n),
+	}
+	for _, x := range db.GetActivities(ctx, username, db.PeriodDay, begin, end) {
+		if x.Submissions > 0 {
+			data.ActiveDays++
+		}
+		t, err := time.ParseInLocation("2006-01-02", x.Period, time.Local)
+		if err != nil {
+			panic(err)
+		}
+		if i := utils.SubDays(heatBegin, t); !t.Before(heatBegin) && i < n {
+			data.Heatmap.Solved[i] = x.Solved
+			data.Heatmap.Submissions[i] = x.Submissions
+		}
+	}
+	dataResponse(w, data)
+}
@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+	"time"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+const defaultAttendanceThreshold = 0.6
+
+func init() {
+	contestRouter.HandleFunc("/check_in", userSelfOrAdminOnly(checkInContest)).Methods("POST")
+	contestRouter.HandleFunc("/excuse", adminOnly(excuseContest)).Methods("POST")
+	contestRouter.HandleFunc("/del_attendance", adminOnly(delAttendance)).Methods("POST")
+	userRouter.HandleFunc("/{username}/attendance", getUserAttendance).Methods("GET")
+	contestGroupRouter.HandleFunc("/{id}/attendance", getGroupAttendance).Methods("GET")
+}
+
+type attendanceSummary struct {
+	Username  string  `json:"username"`
+	Nickname  string  `json:"nickname"`
+	Total     int     `json:"total"`
+	Attended  int     `json:"attended"`
+	Excused   int     `json:"excused"`
+	Absent    int     `json:"absent"`
+	Rate      float64 `json:"rate"`
+	IsFlagged bool    `json:"is_flagged"`
+}
+
+// add update summary by a attendance, Rate is calculated without excused contests
+func (s *attendanceSummary) add(x db.Attendance, threshold float64) {
+	s.Total++
+	switch x.Status {
+	case db.AttendancePresent, db.AttendanceCheckedIn:
+		s.Attended++
+	case db.AttendanceExcused:
+		s.Excused++
+	default:
+		s.Absent++
+	}
+	s.Rate = 1
+	if s.Total > s.Excused {
+		s.Rate = float64(s.Attended) / float64(s.Total-s.Excused)
+	}
+	s.IsFlagged = s.Rate < threshold
+}
+
+func getAttendanceThreshold(r *http.Request) float64 {
+	threshold := config.Instance.AttendanceThreshold
+	if threshold <= 0 {
+		threshold = defaultAttendanceThreshold
+	}
+	return getParamFloat(r, "threshold", threshold)
+}
+
+// checkInContest is allowed from contest start to CheckInGrace minutes after it ends, for users in teams of the contest
+func checkInContest(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		ContestId int    `json:"contest_id"`
+		Username  string `json:"username"`
+	}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	contest := db.GetContestById(ctx, args.ContestId)
+	start := time.Time(contest.StartTime)
+	grace := time.Duration(config.Instance.CheckInGrace) * time.Minute
+	end := start.Add(time.Duration(contest.Duration)*time.Minute + grace)
+	if now := time.Now(); now.Before(start) || now.After(end) {
+		panic(errorx.ErrBadRequest.WithMessage("check-in is only allowed during contest"))
+	}
+	if !db.IsUserInContest(ctx, contest.Id, args.Username) {
+		panic(errorx.ErrBadRequest.WithMessage("user is not in any team of the contest"))
+	}
+	db.UpdAttendanceRecord(ctx, db.AttendanceRecord{
+		ContestId: args.ContestId,
+		Username:  args.Username,
+		Status:    db.AttendanceCheckedIn,
+	})
+	msgResponse(w, http.StatusOK, "签到成功")
+}
+
+func excuseContest(w http.ResponseWriter, r *http.Request) {
+	var record db.AttendanceRecord
+	decodeParamVar(r, &record)
+	record.Status = db.AttendanceExcused
+	db.UpdAttendanceRecord(r.Context(), record)
+	msgResponse(w, http.StatusOK, "请假成功")
+}
+
+func delAttendance(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		ContestId int    `json:"contest_id"`
+		Username  string `json:"username"`
+	}
+	decodeParamVar(r, &args)
+	db.DelAttendanceRecord(r.Context(), args.ContestId, args.Username)
+	msgResponse(w, http.StatusOK, "删除考勤记录成功")
+}
+
+// getUserAttendance return attendance of each contest and the summary of a user
+func getUserAttendance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	username := getParamURL(r, "username")
+	begin, end := getParamDateInterval(r)
+	groupId := getParamInt(r, "group_id", 0)
+	threshold := getAttendanceThreshold(r)
+
+	u := db.MustGetUser(ctx, username)
+	data := struct {
+		Summary  attendanceSummary `json:"summary"`
+		Contests []db.Attendance   `json:"contests"`
+	}{
+		Summary: attendanceSummary{
+			Username: u.Username,
+			Nickname: u.Nickname,
+			Rate:     1,
+		},
+		Contests: db.GetAttendances(ctx, username, groupId, begin, end),
+	}
+	for _, x := range data.Contests {
+		data.Summary.add(x, threshold)
+	}
+	dataResponse(w, data)
+}
+
+// getGroupAttendance return attendance summary of users in contests of a contest group
+// users with the lowest attendance rate come first
+func getGroupAttendance(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	id := getParamIntURL(r, "id")
+	begin, end := getParamDateInterval(r)
+	threshold := getAttendanceThreshold(r)
+
+	mp := make(map[string]*attendanceSummary)
+	for _, x := range db.GetAttendances(ctx, "", id, begin, end) {
+		if _, ok := mp[x.Username]; !ok {
+			mp[x.Username] = &attendanceSummary{
+				Username: x.Username,
+				Nickname: x.Nickname,
+			}
+		}
+		mp[x.Username].add(x, threshold)
+	}
+	data := make([]attendanceSummary, 0)
+	for _, v := range mp {
+		data = append(data, *v)
+	}
+	sort.SliceStable(data, func(i, j int) bool {
+		if data[i].Rate != data[j].Rate {
+			return data[i].Rate < data[j].Rate
+		}
+		return data[i].Username < data[j].Username
+	})
+	dataResponse(w, data)
+}
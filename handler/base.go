@@ -80,6 +80,11 @@ func corsMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// streamRoutes are names of long-lived routes without the request timeout
+var streamRoutes = map[string]bool{
+	notificationStreamRoute: true,
+}
+
 // baseMiddleware logging and handle panic
 func baseMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -107,6 +112,11 @@ func baseMiddleware(next http.Handler) http.Handler {
 			}
 		}()
 		log.Info(r.RequestURI)
+		// server-sent events last until the client disconnects
+		if route := mux.CurrentRoute(r); route != nil && streamRoutes[route.GetName()] {
+			next.ServeHTTP(w, r)
+			return
+		}
 		ctx, cancel := context.WithTimeout(r.Context(), 5*time.Second)
 		defer cancel()
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -0,0 +1,196 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+func init() {
+	Router.HandleFunc("/calendar.ics", getCalendar).Methods("GET")
+	userRouter.HandleFunc("/{username}/calendar_token", userSelfOrAdminOnly(getUserCalendarToken)).Methods("GET")
+	contestGroupRouter.HandleFunc("/{id}/calendar_token", adminOnly(getGroupCalendarToken)).Methods("GET")
+}
+
+// contests starting in [now-calendarPast, now+calendarFuture] are in the feed
+const (
+	calendarPast   = 7 * 24 * time.Hour
+	calendarFuture = 180 * 24 * time.Hour
+)
+
+// calendarToken sign the feed subject (like 'user:32001266' or 'group:1') with Secret.CalendarKey
+// so that calendar apps can subscribe the private feed without login, changing the key revokes all tokens
+func calendarToken(subject string) string {
+	key := config.Instance.CalendarKey
+	if key == "" {
+		panic(errorx.ErrForbidden.WithMessage("calendar key is not configured"))
+	}
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(subject))
+	return hex.EncodeToString(mac.Sum(nil))[:32]
+}
+
+func checkCalendarToken(r *http.Request, subject string) {
+	token := getParamRequired(r, "token")
+	if !hmac.Equal([]byte(token), []byte(calendarToken(subject))) {
+		panic(errorx.ErrForbidden.New())
+	}
+}
+
+func getUserCalendarToken(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	token := calendarToken("user:" + username)
+	dataResponse(w, map[string]string{
+		"token": token,
+		"path":  fmt.Sprintf("/calendar.ics?username=%s&token=%s", username, token),
+	})
+}
+
+func getGroupCalendarToken(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	token := calendarToken("group:" + strconv.Itoa(id))
+	dataResponse(w, map[string]string{
+		"token": token,
+		"path":  fmt.Sprintf("/calendar.ics?group_id=%d&token=%s", id, token),
+	})
+}
+
+type vevent struct {
+	Uid         string
+	Summary     string
+	Description string
+	Start       time.Time
+	End         time.Time
+	AllDay      bool
+}
+
+// icsEscape escape TEXT value (RFC 5545 3.3.11)
+func icsEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`).Replace(s)
+}
+
+// icsFold fold content line longer than 75 octets without breaking utf-8 characters
+func icsFold(line string) string {
+	var sb strings.Builder
+	n := 0
+	for _, c := range line {
+		l := len(string(c))
+		if n+l > 75 {
+			sb.WriteString("\r\n ")
+			n = 1
+		}
+		sb.WriteRune(c)
+		n += l
+	}
+	sb.WriteString("\r\n")
+	return sb.String()
+}
+
+func buildCalendar(name string, events []vevent) string {
+	var sb strings.Builder
+	write := func(line string) {
+		sb.WriteString(icsFold(line))
+	}
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+	write("BEGIN:VCALENDAR")
+	write("VERSION:2.0")
+	write("PRODID:-//ZUCCACM//zuccacm-server//CN")
+	write("CALSCALE:GREGORIAN")
+	write("X-WR-CALNAME:" + icsEscape(name))
+	for _, e := range events {
+		write("BEGIN:VEVENT")
+		write("UID:" + e.Uid + "@zuccacm")
+		write("DTSTAMP:" + stamp)
+		if e.AllDay {
+			write("DTSTART;VALUE=DATE:" + e.Start.Format("20060102"))
+			write("DTEND;VALUE=DATE:" + e.End.Format("20060102"))
+		} else {
+			write("DTSTART:" + e.Start.UTC().Format("20060102T150405Z"))
+			write("DTEND:" + e.End.UTC().Format("20060102T150405Z"))
+		}
+		write("SUMMARY:" + icsEscape(e.Summary))
+		if e.Description != "" {
+			write("DESCRIPTION:" + icsEscape(e.Description))
+		}
+		write("END:VEVENT")
+	}
+	write("END:VCALENDAR")
+	return sb.String()
+}
+
+// getCalendar return iCalendar feed of contests, events, history entries and xcpc
+// with username (or group_id) and token, only contests of the user (or group) are included
+// otherwise contests of all groups are included
+func getCalendar(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	now := time.Now()
+	begin, end := now.Add(-calendarPast), now.Add(calendarFuture)
+	name := "ZUCCACM"
+	var contests []db.Contest
+	switch {
+	case r.URL.Query().Has("username"):
+		username := getParamRequired(r, "username")
+		checkCalendarToken(r, "user:"+username)
+		name += " - " + db.MustGetUser(ctx, username).Nickname
+		contests = db.GetContestsByUser(ctx, username, begin, end, 0)
+	case r.URL.Query().Has("group_id"):
+		groupId := getParamInt(r, "group_id", 0)
+		checkCalendarToken(r, "group:"+strconv.Itoa(groupId))
+		name += " - " + db.GetContestGroupById(ctx, groupId).Name
+		contests = db.GetContestsByGroup(ctx, groupId, begin, end, db.Page{})
+	default:
+		contests = db.GetContestsByGroup(ctx, 0, begin, end, db.Page{})
+	}
+
+	events := make([]vevent, 0)
+	for _, c := range contests {
+		start := time.Time(c.StartTime)
+		events = append(events, vevent{
+			Uid:     fmt.Sprintf("contest-%d", c.Id),
+			Summary: c.Name,
+			Start:   start,
+			End:     start.Add(time.Duration(c.Duration) * time.Minute),
+		})
+	}
+	for _, e := range db.GetEvents(ctx, true) {
+		events = append(events, vevent{
+			Uid:     fmt.Sprintf("event-%d", e.Id),
+			Summary: e.Name,
+			Start:   e.Start_time,
+			End:     e.End_time,
+		})
+	}
+	for _, h := range db.GetHistorys(ctx, true) {
+		events = append(events, vevent{
+			Uid:         fmt.Sprintf("history-%d", h.Id),
+			Summary:     h.Name,
+			Description: h.Md,
+			Start:       h.Start_time,
+			End:         h.End_time,
+		})
+	}
+	for _, x := range db.GetXcpcs(ctx) {
+		events = append(events, vevent{
+			Uid:     fmt.Sprintf("xcpc-%d", x.Id),
+			Summary: x.Name,
+			Start:   x.Date,
+			End:     x.Date.AddDate(0, 0, 1),
+			AllDay:  true,
+		})
+	}
+
+	w.Header().Set("content-type", "text/calendar;charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(buildCalendar(name, events))); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,375 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/verdict"
+)
+
+// read-only subset of CLICS Contest API (2020-03), so that ICPC tools can be used with our contests
+// responses are in raw json instead of Response
+var clicsRouter = Router.PathPrefix("/clics/contests/{id}").Subrouter()
+
+func init() {
+	clicsRouter.HandleFunc("", getCLICSContest).Methods("GET")
+	clicsRouter.HandleFunc("/state", getCLICSState).Methods("GET")
+	clicsRouter.HandleFunc("/judgement-types", getCLICSJudgementTypes).Methods("GET")
+	clicsRouter.HandleFunc("/problems", getCLICSProblems).Methods("GET")
+	clicsRouter.HandleFunc("/groups", getCLICSGroups).Methods("GET")
+	clicsRouter.HandleFunc("/organizations", getCLICSOrganizations).Methods("GET")
+	clicsRouter.HandleFunc("/teams", getCLICSTeams).Methods("GET")
+	clicsRouter.HandleFunc("/submissions", getCLICSSubmissions).Methods("GET")
+	clicsRouter.HandleFunc("/judgements", getCLICSJudgements).Methods("GET")
+	clicsRouter.HandleFunc("/event-feed", getCLICSEventFeed).Methods("GET")
+}
+
+const clicsPenaltyTime = penaltyTime
+
+// clicsJudgementOf map verdicts to judgement types, others are regarded as WA
+var clicsJudgementOf = map[string]string{
+	verdict.OK:  "AC",
+	verdict.WA:  "WA",
+	verdict.TLE: "TLE",
+	verdict.MLE: "MLE",
+	verdict.OLE: "OLE",
+	verdict.RE:  "RTE",
+	verdict.PE:  "PE",
+	verdict.CE:  "CE",
+}
+
+type clicsContest struct {
+	Id                       string  `json:"id"`
+	Name                     string  `json:"name"`
+	FormalName               string  `json:"formal_name"`
+	StartTime                string  `json:"start_time"`
+	Duration                 string  `json:"duration"`
+	ScoreboardFreezeDuration *string `json:"scoreboard_freeze_duration"`
+	PenaltyTime              int     `json:"penalty_time"`
+}
+
+type clicsState struct {
+	Started      *string `json:"started"`
+	Ended        *string `json:"ended"`
+	Frozen       *string `json:"frozen"`
+	Thawed       *string `json:"thawed"`
+	Finalized    *string `json:"finalized"`
+	EndOfUpdates *string `json:"end_of_updates"`
+}
+
+type clicsJudgementType struct {
+	Id      string `json:"id"`
+	Name    string `json:"name"`
+	Penalty bool   `json:"penalty"`
+	Solved  bool   `json:"solved"`
+}
+
+type clicsProblem struct {
+	Id            string `json:"id"`
+	Label         string `json:"label"`
+	Name          string `json:"name"`
+	Ordinal       int    `json:"ordinal"`
+	TestDataCount int    `json:"test_data_count"`
+}
+
+// clicsGroup is used as both group and organization
+type clicsGroup struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type clicsTeam struct {
+	Id             string   `json:"id"`
+	Name           string   `json:"name"`
+	DisplayName    string   `json:"display_name"`
+	OrganizationId *string  `json:"organization_id"`
+	GroupIds       []string `json:"group_ids"`
+}
+
+type clicsSubmission struct {
+	Id          string `json:"id"`
+	LanguageId  string `json:"language_id"`
+	ProblemId   string `json:"problem_id"`
+	TeamId      string `json:"team_id"`
+	Time        string `json:"time"`
+	ContestTime string `json:"contest_time"`
+}
+
+type clicsJudgement struct {
+	Id               string `json:"id"`
+	SubmissionId     string `json:"submission_id"`
+	JudgementTypeId  string `json:"judgement_type_id"`
+	StartTime        string `json:"start_time"`
+	StartContestTime string `json:"start_contest_time"`
+	EndTime          string `json:"end_time"`
+	EndContestTime   string `json:"end_contest_time"`
+}
+
+type clicsData struct {
+	Contest        clicsContest
+	State          clicsState
+	JudgementTypes []clicsJudgementType
+	Problems       []clicsProblem
+	Groups         []clicsGroup
+	Organizations  []clicsGroup
+	Teams          []clicsTeam
+	Submissions    []clicsSubmission
+	Judgements     []clicsJudgement
+}
+
+func clicsTime(t time.Time) string {
+	return t.Format("2006-01-02T15:04:05.000Z07:00")
+}
+
+func clicsRelTime(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
+
+// buildCLICS build CLICS objects of a contest from contest, team and submission tables
+// grade groups are used as organizations and other groups as groups
+// only submissions during contest are included, and each user submits for the first team found
+func buildCLICS(ctx context.Context, id int) clicsData {
+	contest := db.GetContestById(ctx, id)
+	start := time.Time(contest.StartTime)
+	end := start.Add(time.Duration(contest.Duration) * time.Minute)
+	data := clicsData{
+		Contest: clicsContest{
+			Id:          strconv.Itoa(contest.Id),
+			Name:        contest.Name,
+			FormalName:  contest.Name,
+			StartTime:   clicsTime(start),
+			Duration:    clicsRelTime(end.Sub(start)),
+			PenaltyTime: clicsPenaltyTime,
+		},
+		JudgementTypes: []clicsJudgementType{
+			{Id: "AC", Name: "correct", Penalty: false, Solved: true},
+			{Id: "WA", Name: "wrong answer", Penalty: true, Solved: false},
+			{Id: "TLE", Name: "time limit exceeded", Penalty: true, Solved: false},
+			{Id: "MLE", Name: "memory limit exceeded", Penalty: true, Solved: false},
+			{Id: "OLE", Name: "output limit exceeded", Penalty: true, Solved: false},
+			{Id: "RTE", Name: "run-time error", Penalty: true, Solved: false},
+			{Id: "PE", Name: "presentation error", Penalty: true, Solved: false},
+			{Id: "CE", Name: "compiler error", Penalty: false, Solved: false},
+		},
+		Problems:      make([]clicsProblem, 0),
+		Groups:        make([]clicsGroup, 0),
+		Organizations: make([]clicsGroup, 0),
+		Teams:         make([]clicsTeam, 0),
+		Submissions:   make([]clicsSubmission, 0),
+		Judgements:    make([]clicsJudgement, 0),
+	}
+	now := time.Now()
+	if now.After(start) {
+		s := clicsTime(start)
+		data.State.Started = &s
+	}
+	if now.After(end) {
+		e := clicsTime(end)
+		data.State.Ended = &e
+		data.State.Finalized = &e
+		data.State.EndOfUpdates = &e
+	}
+
+	type problemKey struct {
+		OjId int
+		Pid  string
+	}
+	problemId := make(map[problemKey]string)
+	for i, p := range contest.Problems {
+		data.Problems = append(data.Problems, clicsProblem{
+			Id:            p.Index,
+			Label:         p.Index,
+			Name:          p.Pid,
+			Ordinal:       i,
+			TestDataCount: 1,
+		})
+		problemId[problemKey{p.OjId, p.Pid}] = p.Index
+	}
+
+	orgOf := make(map[int]string)
+	groupsOf := make(map[int][]string)
+	vis := make(map[int]bool)
+	for _, g := range db.GetTeamGroupsInContest(ctx, id) {
+		gid := strconv.Itoa(g.GroupId)
+		if !vis[g.GroupId] {
+			vis[g.GroupId] = true
+			if g.IsGrade {
+				data.Organizations = append(data.Organizations, clicsGroup{gid, g.GroupName})
+			} else {
+				data.Groups = append(data.Groups, clicsGroup{gid, g.GroupName})
+			}
+		}
+		if g.IsGrade {
+			if _, ok := orgOf[g.TeamId]; !ok {
+				orgOf[g.TeamId] = gid
+			}
+		} else {
+			groupsOf[g.TeamId] = append(groupsOf[g.TeamId], gid)
+		}
+	}
+
+	teamOf := make(map[string]string)
+	teams := db.GetTeamsInContest(ctx, id)
+	sort.SliceStable(teams, func(i, j int) bool {
+		return teams[i].Id < teams[j].Id
+	})
+	for _, t := range teams {
+		tid := strconv.Itoa(t.Id)
+		team := clicsTeam{
+			Id:          tid,
+			Name:        t.Name,
+			DisplayName: t.Name,
+			GroupIds:    make([]string, 0),
+		}
+		if t.IsSelf && len(t.Users) > 0 {
+			team.DisplayName = t.Users[0].Nickname
+		}
+		if org, ok := orgOf[t.Id]; ok {
+			team.OrganizationId = &org
+		}
+		team.GroupIds = append(team.GroupIds, groupsOf[t.Id]...)
+		data.Teams = append(data.Teams, team)
+		for _, u := range t.Users {
+			if _, ok := teamOf[u.Username]; !ok {
+				teamOf[u.Username] = tid
+			}
+		}
+	}
+
+	for _, s := range db.GetSubmissionsInContest(ctx, id) {
+		t := time.Time(s.CreateTime)
+		if t.Before(start) || t.After(end) {
+			continue
+		}
+		sid := strconv.Itoa(s.Id)
+		language := s.Language
+		if language == "" {
+			language = "unknown"
+		}
+		data.Submissions = append(data.Submissions, clicsSubmission{
+			Id:          sid,
+			LanguageId:  language,
+			ProblemId:   problemId[problemKey{s.OjId, s.Pid}],
+			TeamId:      teamOf[s.Username],
+			Time:        clicsTime(t),
+			ContestTime: clicsRelTime(t.Sub(start)),
+		})
+		judgement, ok := clicsJudgementOf[s.Verdict]
+		if !ok {
+			judgement = "WA"
+		}
+		if s.IsAccepted {
+			judgement = "AC"
+		}
+		data.Judgements = append(data.Judgements, clicsJudgement{
+			Id:               sid,
+			SubmissionId:     sid,
+			JudgementTypeId:  judgement,
+			StartTime:        clicsTime(t),
+			StartContestTime: clicsRelTime(t.Sub(start)),
+			EndTime:          clicsTime(t),
+			EndContestTime:   clicsRelTime(t.Sub(start)),
+		})
+	}
+	return data
+}
+
+func clicsResponse(w http.ResponseWriter, data interface{}) {
+	b, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	w.WriteHeader(http.StatusOK)
+	if _, err = w.Write(b); err != nil {
+		panic(err)
+	}
+}
+
+func getCLICSContest(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Contest)
+}
+
+func getCLICSState(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).State)
+}
+
+func getCLICSJudgementTypes(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).JudgementTypes)
+}
+
+func getCLICSProblems(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Problems)
+}
+
+func getCLICSGroups(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Groups)
+}
+
+func getCLICSOrganizations(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Organizations)
+}
+
+func getCLICSTeams(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Teams)
+}
+
+func getCLICSSubmissions(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Submissions)
+}
+
+func getCLICSJudgements(w http.ResponseWriter, r *http.Request) {
+	clicsResponse(w, buildCLICS(r.Context(), getParamIntURL(r, "id")).Judgements)
+}
+
+// getCLICSEventFeed return all objects as create events in NDJSON
+// submissions and judgements are in time order, state is the last event
+func getCLICSEventFeed(w http.ResponseWriter, r *http.Request) {
+	data := buildCLICS(r.Context(), getParamIntURL(r, "id"))
+	type event struct {
+		Id   string      `json:"id"`
+		Type string      `json:"type"`
+		Op   string      `json:"op"`
+		Data interface{} `json:"data"`
+	}
+	events := make([]event, 0)
+	add := func(typ string, v interface{}) {
+		events = append(events, event{strconv.Itoa(len(events) + 1), typ, "create", v})
+	}
+	add("contests", data.Contest)
+	for _, x := range data.JudgementTypes {
+		add("judgement-types", x)
+	}
+	for _, x := range data.Problems {
+		add("problems", x)
+	}
+	for _, x := range data.Groups {
+		add("groups", x)
+	}
+	for _, x := range data.Organizations {
+		add("organizations", x)
+	}
+	for _, x := range data.Teams {
+		add("teams", x)
+	}
+	for i := range data.Submissions {
+		add("submissions", data.Submissions[i])
+		add("judgements", data.Judgements[i])
+	}
+	add("state", data.State)
+
+	w.Header().Set("content-type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	for _, e := range events {
+		if err := enc.Encode(e); err != nil {
+			panic(err)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+func init() {
+	Router.HandleFunc("/leaderboards", getLeaderboards).Methods("GET")
+	Router.HandleFunc("/leaderboard/finalize", adminOnly(finalizeLeaderboard)).Methods("POST")
+	Router.HandleFunc("/leaderboard/{id:[0-9]+}", getLeaderboard).Methods("GET")
+	Router.HandleFunc("/leaderboard/{kind}", getPeriodLeaderboard).Methods("GET")
+}
+
+func checkLeaderboardKind(kind string) {
+	for _, k := range db.LeaderboardKinds {
+		if k == kind {
+			return
+		}
+	}
+	panic(errorx.ErrBadRequest.WithMessage("unknown leaderboard kind: " + kind))
+}
+
+// getLeaderboards return final leaderboards, filtered by kind if given
+func getLeaderboards(w http.ResponseWriter, r *http.Request) {
+	kind := getParam(r, "kind", "")
+	if kind != "" {
+		checkLeaderboardKind(kind)
+	}
+	dataResponse(w, db.GetLeaderboards(r.Context(), kind))
+}
+
+func getLeaderboard(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetLeaderboardById(r.Context(), getParamIntURL(r, "id")))
+}
+
+// getPeriodLeaderboard return the leaderboard of the period which date (today by default) is in
+// it is calculated on the fly if the period has not been finalized
+func getPeriodLeaderboard(w http.ResponseWriter, r *http.Request) {
+	kind := getParamURL(r, "kind")
+	checkLeaderboardKind(kind)
+	t := time.Now()
+	if date := getParam(r, "date", ""); date != "" {
+		t = parseDate(date)
+	}
+	ctx := r.Context()
+	_, begin, _ := db.LeaderboardPeriod(kind, t)
+	if l, ok := db.GetFinalLeaderboard(ctx, kind, begin); ok {
+		dataResponse(w, l)
+		return
+	}
+	dataResponse(w, db.CalcLeaderboard(ctx, kind, t))
+}
+
+// finalizeLeaderboard (re)calculate and persist the leaderboard of the period which date is in
+func finalizeLeaderboard(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Kind string `json:"kind"`
+		Date string `json:"date"`
+	}{}
+	decodeParamVar(r, &args)
+	checkLeaderboardKind(args.Kind)
+	t := parseDate(args.Date)
+	_, _, end := db.LeaderboardPeriod(args.Kind, t)
+	if end.After(time.Now()) {
+		panic(errorx.ErrBadRequest.WithMessage("the period has not ended"))
+	}
+	ctx := r.Context()
+	id := db.SaveLeaderboard(ctx, db.CalcLeaderboard(ctx, args.Kind, t))
+	dataResponse(w, struct {
+		Id int `json:"id"`
+	}{id})
+}
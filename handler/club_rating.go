@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/db"
+	"zuccacm-server/mq"
+	"zuccacm-server/utils"
+)
+
+const (
+	// clubRatingDelay is the time to wait after a contest ends before rating it, for submissions to be pulled
+	clubRatingDelay = 2 * time.Hour
+	// clubRatingLockWait is the time recomputing waits for others (such as the auto task) to finish
+	clubRatingLockWait = 5 * time.Minute
+)
+
+func init() {
+	mq.Schedule("*/10 * * * *", rateEndedContests)
+	ratingRouter.HandleFunc("/club", getClubRatingLeaderboard).Methods("GET")
+	ratingRouter.HandleFunc("/club/recompute", adminOnly(recomputeClubRating)).Methods("POST")
+	userRouter.HandleFunc("/{username}/club_rating", getUserClubRating).Methods("GET")
+}
+
+// calcRatingDeltas return rating changes by the codeforces rating algorithm
+// ranks are places in the contest, tied participants share the last place among them
+func calcRatingDeltas(ratings, ranks []int) []int {
+	n := len(ratings)
+	deltas := make([]int, n)
+	if n < 2 {
+		return deltas
+	}
+	// expected place of a participant with rating r, i is excluded
+	seed := func(r float64, i int) float64 {
+		ret := 1.0
+		for j, x := range ratings {
+			if j != i {
+				ret += 1 / (1 + math.Pow(10, (r-float64(x))/400))
+			}
+		}
+		return ret
+	}
+	sum := 0
+	for i, r := range ratings {
+		m := math.Sqrt(seed(float64(r), i) * float64(ranks[i]))
+		lo, hi := 1, 8000
+		for hi-lo > 1 {
+			mid := (lo + hi) / 2
+			if seed(float64(mid), i) < m {
+				hi = mid
+			} else {
+				lo = mid
+			}
+		}
+		deltas[i] = (lo - r) / 2
+		sum += deltas[i]
+	}
+	// total change should be slightly negative to avoid inflation
+	inc := -sum/n - 1
+	for i := range deltas {
+		deltas[i] += inc
+	}
+	// total change of top participants should not be positive
+	idx := make([]int, n)
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(x, y int) bool {
+		return ratings[idx[x]] > ratings[idx[y]]
+	})
+	s := utils.Min(n, 4*int(math.Round(math.Sqrt(float64(n)))))
+	sum = 0
+	for _, i := range idx[:s] {
+		sum += deltas[i]
+	}
+	inc = utils.Min(utils.Max(-sum/s, -10), 0)
+	for i := range deltas {
+		deltas[i] += inc
+	}
+	return deltas
+}
+
+// participated return whether there is any submission of the row during the contest
+func participated(row standingRow, c db.Contest) bool {
+	end := time.Time(c.StartTime).Add(time.Duration(c.Duration) * time.Minute)
+	for _, pr := range row.ProblemResults {
+		for _, s := range pr.Submissions {
+			if !time.Time(s.CreateTime).After(end) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rateContest return club ratings of participants of the contest based on current ratings
+// members of a team which has submissions during the contest share the place of the team
+// teams are placed as in standings (see betterStanding), virtual participations are not rated
+func rateContest(ctx context.Context, contest db.Contest, current map[string]int) []db.Rating {
+	s := loadContestStandings(ctx, contest)
+	s.filterVirtual()
+	type participant struct {
+		username string
+		row      standingRow
+	}
+	participants := make([]participant, 0)
+	seen := make(map[string]bool)
+	for _, x := range s.Standings {
+		rows := x.Users
+		if rows == nil {
+			rows = []standingRow{x.Team}
+		}
+		ok := false
+		for _, row := range rows {
+			ok = ok || participated(row, contest)
+		}
+		if !ok {
+			continue
+		}
+		for _, row := range rows {
+			// a user in several teams is rated by the best one
+			if !seen[row.Id] {
+				seen[row.Id] = true
+				participants = append(participants, participant{row.Id, x.Team})
+			}
+		}
+	}
+	ret := make([]db.Rating, 0)
+	if len(participants) < 2 {
+		return ret
+	}
+	ratings := make([]int, len(participants))
+	ranks := make([]int, len(participants))
+	for i, p := range participants {
+		ratings[i] = db.ClubInitialRating
+		if r, ok := current[p.username]; ok {
+			ratings[i] = r
+		}
+		// tied participants share the last place among them
+		for _, q := range participants {
+			if !betterStanding(contest, p.row, q.row) {
+				ranks[i]++
+			}
+		}
+	}
+	deltas := calcRatingDeltas(ratings, ranks)
+	for i, p := range participants {
+		ret = append(ret, db.Rating{
+			Username:    p.username,
+			OjId:        db.ClubOjId,
+			Rating:      ratings[i] + deltas[i],
+			ContestRank: ranks[i],
+			ContestTime: time.Time(contest.StartTime),
+			ContestName: contest.Name,
+			Delta:       deltas[i],
+		})
+	}
+	return ret
+}
+
+// rateContests rate contests in order and return the number of rated contests
+func rateContests(ctx context.Context, contests []db.Contest) int {
+	current := db.GetClubRatings(ctx)
+	for _, c := range contests {
+		ratings := rateContest(ctx, db.GetContestById(ctx, c.Id), current)
+		db.AddClubRating(ctx, c.Id, ratings)
+		for _, r := range ratings {
+			current[r.Username] = r.Rating
+		}
+		log.WithFields(log.Fields{
+			"contest_id":   c.Id,
+			"participants": len(ratings),
+		}).Info("contest has been rated")
+	}
+	return len(contests)
+}
+
+// rateEndedContests is an auto task to rate contests which have ended for clubRatingDelay
+// it is skipped if club ratings are being written by others, and recomputes if an earlier contest is unrated
+func rateEndedContests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+	defer cancel()
+	unlock, ok := db.LockClubRating(ctx, 0)
+	if !ok {
+		log.Info("club rating is being written by others, skip rating ended contests")
+		return
+	}
+	defer unlock()
+	end := time.Now().Add(-clubRatingDelay)
+	if db.HasEarlierUnratedContest(ctx, end) {
+		n := recomputeClubRatingLocked(ctx)
+		log.WithField("contests", n).Info("club rating has been recomputed for an earlier unrated contest")
+		return
+	}
+	rateContests(ctx, db.GetUnratedContests(ctx, end))
+}
+
+func recomputeClubRatingLocked(ctx context.Context) int {
+	db.ClearClubRating(ctx)
+	return rateContests(ctx, db.GetUnratedContests(ctx, time.Now().Add(-clubRatingDelay)))
+}
+
+// RecomputeClubRating clear and rate all ended contests from scratch, return the number of rated contests
+// ok=false if club ratings are being written by others for longer than clubRatingLockWait
+func RecomputeClubRating(ctx context.Context) (n int, ok bool) {
+	unlock, ok := db.LockClubRating(ctx, clubRatingLockWait)
+	if !ok {
+		return 0, false
+	}
+	defer unlock()
+	return recomputeClubRatingLocked(ctx), true
+}
+
+func getClubRatingLeaderboard(w http.ResponseWriter, r *http.Request) {
+	isEnable := getParamBool(r, "is_enable", true)
+	dataResponse(w, db.GetClubRatingLeaderboard(r.Context(), isEnable))
+}
+
+func getUserClubRating(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	ctx := r.Context()
+	db.MustGetUser(ctx, username)
+	history := db.GetRatingHistory(ctx, username, db.ClubOjId)
+	rating, maxRating := db.ClubInitialRating, 0
+	for _, x := range history {
+		rating = x.Rating
+		maxRating = utils.Max(maxRating, x.Rating)
+	}
+	dataResponse(w, struct {
+		Rating    int               `json:"rating"`
+		MaxRating int               `json:"max_rating"`
+		History   []db.RatingChange `json:"history"`
+	}{rating, maxRating, history})
+}
+
+// recomputeClubRating run in background since it may take long
+func recomputeClubRating(w http.ResponseWriter, r *http.Request) {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.WithField("error", err).Error("recompute club rating failed")
+			}
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute*30)
+		defer cancel()
+		n, ok := RecomputeClubRating(ctx)
+		if !ok {
+			log.Warn("club rating is being written by others, recomputing is skipped")
+			return
+		}
+		log.WithField("contests", n).Info("club rating has been recomputed")
+	}()
+	msgResponse(w, http.StatusOK, "任务已创建：重算俱乐部Rating")
+}
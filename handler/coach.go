@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+var coachRouter = Router.PathPrefix("/coach").Subrouter()
+
+func init() {
+	coachRouter.HandleFunc("/at_risk", adminOnly(getAtRiskMembers)).Methods("GET")
+	coachRouter.HandleFunc("/alerts", adminOnly(getMemberAlerts)).Methods("GET")
+	coachRouter.HandleFunc("/ack_alert", adminOnly(ackMemberAlert)).Methods("POST")
+}
+
+// getAtRiskMembers detect at-risk members now, thresholds in config can be overridden by params
+func getAtRiskMembers(w http.ResponseWriter, r *http.Request) {
+	cfg := config.Instance.TrainingConfig
+	th := db.NewAlertThreshold(cfg.InactiveDays, cfg.DropRatio, cfg.MinWeeklySolved)
+	th.InactiveDays = getParamInt(r, "inactive_days", th.InactiveDays)
+	th.DropRatio = getParamFloat(r, "drop_ratio", th.DropRatio)
+	th.MinWeeklySolved = getParamFloat(r, "min_weekly_solved", th.MinWeeklySolved)
+	if th.InactiveDays <= 0 || th.DropRatio <= 0 || th.DropRatio > 1 {
+		panic(errorx.ErrBadRequest.WithMessage("invalid thresholds"))
+	}
+	dataResponse(w, struct {
+		Threshold db.AlertThreshold `json:"threshold"`
+		Members   []db.AtRiskMember `json:"members"`
+	}{th, db.DetectAtRiskMembers(r.Context(), th, time.Now())})
+}
+
+// getMemberAlerts return alerts added by the daily scan, only pending ones by default
+func getMemberAlerts(w http.ResponseWriter, r *http.Request) {
+	pendingOnly := getParamBool(r, "pending", true)
+	dataResponse(w, db.GetMemberAlerts(r.Context(), pendingOnly, decodePage(r)))
+}
+
+func ackMemberAlert(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Id   int    `json:"id"`
+		Note string `json:"note"`
+	}{}
+	decodeParamVar(r, &args)
+	if args.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("id can't be empty or zero"))
+	}
+	db.AckMemberAlert(r.Context(), args.Id, args.Note)
+	msgResponse(w, http.StatusOK, "确认提醒成功")
+}
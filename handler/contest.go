@@ -2,12 +2,12 @@ package handler
 
 import (
 	"net/http"
-	"sort"
-	"strconv"
+	"time"
 
 	"zuccacm-server/db"
 	"zuccacm-server/enum/errorx"
 	"zuccacm-server/mq"
+	"zuccacm-server/webhook"
 )
 
 var contestRouter = Router.PathPrefix("/contest").Subrouter()
@@ -19,6 +19,8 @@ func init() {
 	contestRouter.HandleFunc("/del", adminOnly(delContest)).Methods("POST")
 	contestRouter.HandleFunc("/refresh", adminOnly(refreshContest)).Methods("POST")
 	contestRouter.HandleFunc("/pull", pullContest).Methods("POST")
+	contestRouter.HandleFunc("/snapshot", adminOnly(snapshotContest)).Methods("POST")
+	contestRouter.HandleFunc("/{id}/clone", adminOnly(cloneContest)).Methods("POST")
 
 	Router.HandleFunc("/contests", getAllContests).Methods("GET")
 	Router.HandleFunc("/contests/overview", getContestsOverview).Methods("GET")
@@ -73,6 +75,8 @@ func getContest(w http.ResponseWriter, r *http.Request) {
 		Duration     int               `json:"duration"`
 		MaxSolved    int               `json:"max_solved"`
 		Participants int               `json:"participants"`
+		ScoringMode  string            `json:"scoring_mode"`
+		ScoreRule    string            `json:"score_rule"`
 		Problems     []db.Problem      `json:"problems"`
 		Groups       []db.ContestGroup `json:"groups"`
 		Teams        []db.Team         `json:"teams"`
@@ -85,6 +89,8 @@ func getContest(w http.ResponseWriter, r *http.Request) {
 		Duration:     contest.Duration,
 		MaxSolved:    contest.MaxSolved,
 		Participants: contest.Participants,
+		ScoringMode:  contest.ScoringMode,
+		ScoreRule:    contest.ScoreRule,
 		Problems:     contest.Problems,
 		Groups:       groups,
 		Teams:        teams,
@@ -93,115 +99,117 @@ func getContest(w http.ResponseWriter, r *http.Request) {
 }
 
 // getContestStandings return contest info and standing
+// standings of finished contests are served from snapshot unless live=true
+// standings of virtual participations are hidden if show_virtual=false
 func getContestStandings(w http.ResponseWriter, r *http.Request) {
-	type Row struct {
-		Id             string          `json:"id"`
-		Name           string          `json:"name"`
-		Solved         int             `json:"solved"`
-		ProblemResults []problemResult `json:"problem_results"`
-	}
-	type standing struct {
-		Team  Row   `json:"team"`
-		Users []Row `json:"users"`
-	}
 	var data struct {
-		Contest   db.Contest `json:"contest"`
-		Standings []standing `json:"standings"`
+		contestStandings
+		IsSnapshot   bool         `json:"is_snapshot"`
+		SnapshotTime *db.Datetime `json:"snapshot_time,omitempty"`
 	}
 	id := getParamIntURL(r, "id")
+	live := getParamBool(r, "live", false)
+	showVirtual := getParamBool(r, "show_virtual", true)
 	ctx := r.Context()
 
-	sub := db.GetSubmissionsInContest(ctx, id)
-	type Key struct {
-		Username string
-		OjId     int
-		Pid      string
+	contest := db.GetContestById(ctx, id)
+	if live || !isContestEnded(contest) {
+		data.contestStandings = calcContestStandings(ctx, contest, nil)
+	} else {
+		var snapshotTime time.Time
+		data.contestStandings, snapshotTime = loadStandingSnapshot(ctx, contest)
+		data.IsSnapshot = true
+		data.SnapshotTime = (*db.Datetime)(&snapshotTime)
 	}
-	mpSub := make(map[Key][]submissionInfo)
-	for _, s := range sub {
-		key := Key{s.Username, s.OjId, s.Pid}
-		mpSub[key] = append(mpSub[key], submissionInfo{s.IsAccepted, s.CreateTime})
+	if !showVirtual {
+		data.filterVirtual()
 	}
-	data.Standings = make([]standing, 0)
+	dataResponse(w, data)
+}
 
-	contest := db.GetContestById(ctx, id)
-	teams := db.GetTeamsInContest(ctx, id)
-	for _, t := range teams {
-		x := standing{
-			Team: Row{
-				Id:             strconv.Itoa(t.Id),
-				Name:           t.Name,
-				ProblemResults: make([]problemResult, len(contest.Problems)),
-			},
-			Users: make([]Row, 0),
-		}
-		// init team Row
-		for i := range contest.Problems {
-			x.Team.ProblemResults[i] = calcProblemResult(nil, contest.StartTime, contest.Duration)
-		}
-		for _, u := range t.Users {
-			uRow := Row{
-				Id:             u.Username,
-				Name:           u.Nickname,
-				ProblemResults: make([]problemResult, len(contest.Problems)),
-			}
-			for i, p := range contest.Problems {
-				key := Key{u.Username, p.OjId, p.Pid}
-				uRow.ProblemResults[i] = calcProblemResult(mpSub[key], contest.StartTime, contest.Duration)
-			}
-			for _, pr := range uRow.ProblemResults {
-				if pr.AcceptedTime != -1 {
-					uRow.Solved++
-				}
-			}
-			// upd team Row
-			for i, pr := range uRow.ProblemResults {
-				x.Team.ProblemResults[i] = maxResult(x.Team.ProblemResults[i], pr)
-			}
-			x.Users = append(x.Users, uRow)
-		}
-		for _, pr := range x.Team.ProblemResults {
-			if pr.AcceptedTime != -1 {
-				x.Team.Solved++
-			}
-		}
-		// self_team should have no user, normal_team should have no submission
-		if t.IsSelf {
-			x.Team.Id = t.Users[0].Username
-			x.Team.Name = t.Users[0].Nickname
-			x.Users = nil
-		} else {
-			for i := range x.Team.ProblemResults {
-				x.Team.ProblemResults[i].Submissions = nil
-			}
-		}
-		data.Standings = append(data.Standings, x)
+// snapshotContest recalc the snapshot of a finished contest from submissions in db
+func snapshotContest(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Id int `json:"id"`
+	}{}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	contest := db.GetContestById(ctx, args.Id)
+	if !isContestEnded(contest) {
+		panic(errorx.ErrBadRequest.WithMessage("contest has not ended yet"))
 	}
-	sort.SliceStable(data.Standings, func(i, j int) bool {
-		if data.Standings[i].Team.Solved == data.Standings[j].Team.Solved {
-			return data.Standings[i].Team.Name < data.Standings[j].Team.Name
-		}
-		return data.Standings[i].Team.Solved > data.Standings[j].Team.Solved
-	})
-	oj := db.OJMapItoS(db.GetAllOJ(ctx))
-	for i, p := range contest.Problems {
-		contest.Problems[i].ProblemURL = getProblemURL(oj[p.OjId], p.Pid)
+	saveStandingSnapshot(ctx, contest, true)
+	msgResponse(w, http.StatusOK, "重新生成榜单快照成功")
+}
+
+// checkScoringMode panic if scoring mode or score rule is not supported, empty is allowed
+func checkScoringMode(c db.Contest) {
+	switch c.ScoringMode {
+	case "", db.ScoringModeICPC, db.ScoringModeOI:
+	default:
+		panic(errorx.ErrBadRequest.WithMessage("unknown scoring mode: " + c.ScoringMode))
+	}
+	switch c.ScoreRule {
+	case "", db.ScoreRuleBest, db.ScoreRuleLast:
+	default:
+		panic(errorx.ErrBadRequest.WithMessage("unknown score rule: " + c.ScoreRule))
 	}
-	data.Contest = contest
-	dataResponse(w, data)
 }
 
+// addContest add a contest, empty fields will be filled with the template if template_id is given
 func addContest(w http.ResponseWriter, r *http.Request) {
-	var contest db.Contest
-	contest.StartTime = db.Datetime(defaultBeginTime)
-	decodeParamVar(r, &contest)
+	var args struct {
+		db.Contest
+		TemplateId int `json:"template_id"`
+	}
+	args.StartTime = db.Datetime(defaultBeginTime)
+	decodeParamVar(r, &args)
+	contest := args.Contest
+	if args.TemplateId > 0 {
+		t := db.GetContestTemplateById(r.Context(), args.TemplateId)
+		t.Apply(&contest)
+	}
+	checkScoringMode(contest)
 	contest = db.AddContest(r.Context(), contest)
+	webhook.Emit(webhook.ContestCreated, contest)
 	if contest.OjId > 0 {
 		mq.ContestTask(contest.OjId, contest.Id, contest.Cid)
 	}
 	msgResponse(w, http.StatusOK, "添加比赛成功")
 }
 
+// cloneContest copy problems, groups and teams of a contest with a new start time
+// the cid is cleared unless a new one is given, and the oj contest is refreshed only then
+func cloneContest(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Name      string       `json:"name"`
+		Cid       string       `json:"cid"`
+		StartTime *db.Datetime `json:"start_time"`
+	}
+	decodeParamVar(r, &args)
+	if args.StartTime == nil {
+		panic(errorx.ErrBadRequest.WithMessage("start_time can't be empty"))
+	}
+	ctx := r.Context()
+	id := getParamIntURL(r, "id")
+	contest := db.GetContestById(ctx, id)
+	contest.Groups, contest.Teams = db.GetContestRelIds(ctx, id)
+	contest.StartTime = *args.StartTime
+	if args.Name != "" {
+		contest.Name = args.Name
+	}
+	contest.Cid = args.Cid
+	contest.MaxSolved, contest.Participants = 0, 0
+	contest = db.AddContest(ctx, contest)
+	webhook.Emit(webhook.ContestCreated, contest)
+	if contest.OjId > 0 && args.Cid != "" {
+		mq.ContestTask(contest.OjId, contest.Id, contest.Cid)
+	}
+	dataResponse(w, struct {
+		Id int `json:"id"`
+	}{contest.Id})
+}
+
 func updContest(w http.ResponseWriter, r *http.Request) {
 	var contest db.Contest
 	contest.StartTime = db.Datetime(defaultBeginTime)
@@ -209,9 +217,14 @@ func updContest(w http.ResponseWriter, r *http.Request) {
 	if contest.Id == 0 {
 		panic(errorx.ErrBadRequest.WithMessage("contest.id can't be empty or zero"))
 	}
+	checkScoringMode(contest)
 	db.UpdContest(r.Context(), contest)
+	refreshStandingSnapshots(r.Context(), []int{contest.Id})
 	if contest.OjId > 0 {
 		mq.ContestTask(contest.OjId, contest.Id, contest.Cid)
+		if contest.Cid != "" {
+			db.SetContestDispatched(r.Context(), contest.Id)
+		}
 	}
 	msgResponse(w, http.StatusOK, "修改比赛成功")
 }
@@ -281,6 +294,7 @@ func pullContest(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 	db.PullContest(ctx, contest)
+	refreshStandingSnapshots(ctx, []int{contest.Id})
 	msgResponse(w, http.StatusOK, "pull contest success")
 }
 
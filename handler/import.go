@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/importer"
+	"zuccacm-server/webhook"
+)
+
+func init() {
+	contestRouter.HandleFunc("/import", adminOnly(importContest)).Methods("POST")
+	problemRouter.HandleFunc("/import_problemset", adminOnly(importProblemset)).Methods("POST")
+}
+
+// importContest create contest from offline data of codeforces, atcoder or domjudge
+// data is encoded in base64, see importer for details of formats
+func importContest(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Format          string       `json:"format"`
+		Data            []byte       `json:"data"`
+		OJ              string       `json:"oj"`
+		Name            string       `json:"name"`
+		StartTime       *db.Datetime `json:"start_time"`
+		Duration        int          `json:"duration"`
+		Groups          []int        `json:"groups"`
+		Teams           []int        `json:"teams"`
+		WithSubmissions bool         `json:"with_submissions"`
+	}
+	decodeParamVar(r, &args)
+	opt := importer.Options{
+		OJ:              args.OJ,
+		Name:            args.Name,
+		Duration:        args.Duration,
+		Groups:          args.Groups,
+		Teams:           args.Teams,
+		WithSubmissions: args.WithSubmissions,
+	}
+	if args.StartTime != nil {
+		opt.StartTime = time.Time(*args.StartTime)
+	}
+	contest, inserted, err := importer.Import(r.Context(), args.Format, args.Data, opt)
+	if err != nil {
+		panic(errorx.ErrBadRequest.Wrap(err))
+	}
+	webhook.Emit(webhook.ContestCreated, contest)
+	dataResponse(w, struct {
+		ContestId   int   `json:"contest_id"`
+		Problems    int   `json:"problems"`
+		Submissions int64 `json:"submissions"`
+	}{contest.Id, len(contest.Problems), inserted})
+}
+
+// importProblemset upsert problem metadata from the response of codeforces api problemset.problems
+// data is encoded in base64
+func importProblemset(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		Data []byte `json:"data"`
+	}
+	decodeParamVar(r, &args)
+	n, err := importer.ImportProblemset(r.Context(), args.Data)
+	if err != nil {
+		panic(errorx.ErrBadRequest.Wrap(err))
+	}
+	dataResponse(w, struct {
+		Problems int `json:"problems"`
+	}{n})
+}
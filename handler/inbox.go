@@ -0,0 +1,152 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+func init() {
+	userRouter.HandleFunc("/read_notification", userSelfOrAdminOnly(readNotification)).Methods("POST")
+	userRouter.HandleFunc("/read_all_notifications", userSelfOrAdminOnly(readAllNotifications)).Methods("POST")
+	userRouter.HandleFunc("/{username}/notifications", userSelfOrAdminOnly(getNotifications)).Methods("GET")
+	userRouter.HandleFunc("/{username}/notifications/stream", userSelfOrAdminOnly(streamNotifications)).
+		Methods("GET").Name(notificationStreamRoute)
+}
+
+// notificationStreamRoute is the route name of streamNotifications
+const notificationStreamRoute = "notification_stream"
+
+// sseHeartbeat keep the stream alive through proxies
+const sseHeartbeat = 30 * time.Second
+
+// inboxHub dispatch new notifications to SSE streams of this server
+type inboxHub struct {
+	mu   sync.Mutex
+	subs map[string]map[chan db.Notification]struct{}
+}
+
+var hub = &inboxHub{subs: make(map[string]map[chan db.Notification]struct{})}
+
+func (h *inboxHub) subscribe(username string) chan db.Notification {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	ch := make(chan db.Notification, 16)
+	if h.subs[username] == nil {
+		h.subs[username] = make(map[chan db.Notification]struct{})
+	}
+	h.subs[username][ch] = struct{}{}
+	return ch
+}
+
+func (h *inboxHub) unsubscribe(username string, ch chan db.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subs[username], ch)
+	if len(h.subs[username]) == 0 {
+		delete(h.subs, username)
+	}
+}
+
+// publish never blocks, notification is dropped for slow streams (it can still be listed)
+func (h *inboxHub) publish(n db.Notification) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[n.Username] {
+		select {
+		case ch <- n:
+		default:
+		}
+	}
+}
+
+// pushNotification add notification to the inbox of users and stream it to them
+func pushNotification(ctx context.Context, usernames []string, kind, content string) {
+	for _, n := range db.AddNotifications(ctx, usernames, kind, content) {
+		hub.publish(n)
+	}
+}
+
+// pushTeamNotification push notification to members of the team
+func pushTeamNotification(ctx context.Context, teamId int, kind, content string) {
+	pushNotification(ctx, db.GetTeamUsernames(ctx, teamId), kind, content)
+}
+
+func getNotifications(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	unreadOnly := getParamBool(r, "unread", false)
+	page := decodePage(r)
+	ctx := r.Context()
+	dataResponse(w, map[string]interface{}{
+		"unread":        db.GetUnreadCount(ctx, username),
+		"notifications": db.GetNotifications(ctx, username, unreadOnly, page),
+	})
+}
+
+func readNotification(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Username string `json:"username"`
+		Id       int    `json:"id"`
+	}{}
+	decodeParamVar(r, &args)
+	if args.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("id can't be empty or zero"))
+	}
+	db.ReadNotification(r.Context(), args.Username, args.Id)
+	msgResponse(w, http.StatusOK, "已读成功")
+}
+
+func readAllNotifications(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Username string `json:"username"`
+	}{}
+	decodeParamVar(r, &args)
+	db.ReadAllNotifications(r.Context(), args.Username)
+	msgResponse(w, http.StatusOK, "全部已读成功")
+}
+
+// streamNotifications push new notifications of the user as server-sent events
+// the stream lasts until the client disconnects
+func streamNotifications(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		panic(errorx.ErrBadRequest.WithMessage("streaming is not supported"))
+	}
+	ch := hub.subscribe(username)
+	defer hub.unsubscribe(username, ch)
+
+	w.Header().Set("content-type", "text/event-stream")
+	w.Header().Set("cache-control", "no-cache")
+	w.Header().Set("connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ticker := time.NewTicker(sseHeartbeat)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return
+			}
+		case n := <-ch:
+			b, err := json.Marshal(n)
+			if err != nil {
+				panic(err)
+			}
+			if _, err = fmt.Fprintf(w, "id: %d\nevent: notification\ndata: %s\n\n", n.Id, b); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
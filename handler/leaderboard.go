@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/utils"
+)
+
+func init() {
+	contestGroupRouter.HandleFunc("/upd_scoring", adminOnly(updGroupScoring)).Methods("POST")
+	contestGroupRouter.HandleFunc("/{id}/scoring", getGroupScoring).Methods("GET")
+	contestGroupRouter.HandleFunc("/{id}/leaderboard", getGroupLeaderboard).Methods("GET")
+}
+
+type seriesContest struct {
+	ContestId   int         `json:"contest_id"`
+	ContestName string      `json:"contest_name"`
+	StartTime   db.Datetime `json:"start_time"`
+}
+
+// seriesResult is the result of a user in a contest, Rank=0 means absent
+type seriesResult struct {
+	Rank     int     `json:"rank"`
+	Solved   int     `json:"solved"`
+	Penalty  int     `json:"penalty"`
+	Upsolved int     `json:"upsolved"`
+	Points   float64 `json:"points"`
+	Dropped  bool    `json:"dropped"`
+}
+
+type seriesRow struct {
+	Username string         `json:"username"`
+	Nickname string         `json:"nickname"`
+	Points   float64        `json:"points"`
+	Results  []seriesResult `json:"results"`
+}
+
+type seriesLeaderboard struct {
+	Scoring  db.GroupScoring `json:"scoring"`
+	Contests []seriesContest `json:"contests"`
+	Rows     []seriesRow     `json:"rows"`
+}
+
+// countResults return the number of problems solved in contest and upsolved after contest
+func countResults(row standingRow, duration int) (solved, upsolved int) {
+	for _, pr := range row.ProblemResults {
+		if pr.AcceptedTime == duration+1 {
+			upsolved++
+		} else if pr.AcceptedTime != -1 {
+			solved++
+		}
+	}
+	return
+}
+
+// countPenalty return the penalty time of problems solved in contest
+func countPenalty(row standingRow, duration int) (penalty int) {
+	for _, pr := range row.ProblemResults {
+		if pr.AcceptedTime >= 0 && pr.AcceptedTime <= duration {
+			penalty += pr.AcceptedTime + penaltyTime*pr.Dirt
+		}
+	}
+	return
+}
+
+// betterStanding return whether row x is placed before row y in the contest
+// rows are ranked by score in oi mode, otherwise by solved and penalty during the contest
+func betterStanding(contest db.Contest, x, y standingRow) bool {
+	if contest.ScoringMode == db.ScoringModeOI {
+		return x.Score > y.Score
+	}
+	xs, _ := countResults(x, contest.Duration)
+	ys, _ := countResults(y, contest.Duration)
+	if xs != ys {
+		return xs > ys
+	}
+	return countPenalty(x, contest.Duration) < countPenalty(y, contest.Duration)
+}
+
+// contestPoints return points of a result by the scoring rule
+// maxSolved is used by normalized rule only
+func contestPoints(s db.GroupScoring, x seriesResult, maxSolved int) float64 {
+	switch s.Rule {
+	case db.ScoringRank:
+		points := 0.0
+		if x.Solved > 0 && x.Rank <= len(s.RankPoints) {
+			points = float64(s.RankPoints[x.Rank-1])
+		}
+		return points + s.UpsolveWeight*float64(x.Upsolved)
+	case db.ScoringNormalized:
+		if maxSolved <= 0 {
+			return 0
+		}
+		return 100 * (float64(x.Solved) + s.UpsolveWeight*float64(x.Upsolved)) / float64(maxSolved)
+	default:
+		return float64(x.Solved) + s.UpsolveWeight*float64(x.Upsolved)
+	}
+}
+
+// calcSeriesLeaderboard calc leaderboard over contests in group during [begin, end]
+// members of a normal team share the rank and result of their team
+func calcSeriesLeaderboard(ctx context.Context, groupId int, begin, end time.Time) seriesLeaderboard {
+	scoring := db.GetGroupScoring(ctx, groupId)
+	contests := db.GetContestsByGroup(ctx, groupId, begin, end, db.Page{})
+	sort.SliceStable(contests, func(i, j int) bool {
+		return contests[i].StartTime.Unix() < contests[j].StartTime.Unix()
+	})
+	data := seriesLeaderboard{
+		Scoring:  scoring,
+		Contests: make([]seriesContest, 0),
+		Rows:     make([]seriesRow, 0),
+	}
+	mpRow := make(map[string]int)
+	getRow := func(username, nickname string) *seriesRow {
+		if i, ok := mpRow[username]; ok {
+			return &data.Rows[i]
+		}
+		mpRow[username] = len(data.Rows)
+		data.Rows = append(data.Rows, seriesRow{
+			Username: username,
+			Nickname: nickname,
+			Results:  make([]seriesResult, len(contests)),
+		})
+		return &data.Rows[len(data.Rows)-1]
+	}
+
+	for k, c := range contests {
+		data.Contests = append(data.Contests, seriesContest{
+			ContestId:   c.Id,
+			ContestName: c.Name,
+			StartTime:   c.StartTime,
+		})
+		contest := db.GetContestById(ctx, c.Id)
+		s := peekContestStandings(ctx, contest)
+		s.filterVirtual()
+		results := make([]seriesResult, len(s.Standings))
+		maxSolved := contest.MaxSolved
+		for i, x := range s.Standings {
+			results[i].Solved, results[i].Upsolved = countResults(x.Team, contest.Duration)
+			results[i].Penalty = countPenalty(x.Team, contest.Duration)
+			if contest.MaxSolved <= 0 {
+				maxSolved = utils.Max(maxSolved, results[i].Solved)
+			}
+		}
+		for i := range results {
+			results[i].Rank = 1
+			for j := range results {
+				if betterStanding(contest, s.Standings[j].Team, s.Standings[i].Team) {
+					results[i].Rank++
+				}
+			}
+		}
+		for i, x := range s.Standings {
+			results[i].Points = contestPoints(scoring, results[i], maxSolved)
+			if x.Users == nil {
+				getRow(x.Team.Id, x.Team.Name).Results[k] = results[i]
+			}
+			for _, u := range x.Users {
+				getRow(u.Id, u.Name).Results[k] = results[i]
+			}
+		}
+	}
+
+	for i := range data.Rows {
+		row := &data.Rows[i]
+		idx := make([]int, len(row.Results))
+		for j := range idx {
+			idx[j] = j
+		}
+		sort.SliceStable(idx, func(x, y int) bool {
+			return row.Results[idx[x]].Points < row.Results[idx[y]].Points
+		})
+		for j, x := range idx {
+			if j < scoring.DropWorst {
+				row.Results[x].Dropped = true
+			} else {
+				row.Points += row.Results[x].Points
+			}
+		}
+	}
+	sort.SliceStable(data.Rows, func(i, j int) bool {
+		if data.Rows[i].Points != data.Rows[j].Points {
+			return data.Rows[i].Points > data.Rows[j].Points
+		}
+		return data.Rows[i].Username < data.Rows[j].Username
+	})
+	return data
+}
+
+func getGroupLeaderboard(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	begin, end := getParamDateInterval(r)
+	dataResponse(w, calcSeriesLeaderboard(r.Context(), id, begin, end))
+}
+
+func getGroupScoring(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	dataResponse(w, db.GetGroupScoring(r.Context(), id))
+}
+
+func updGroupScoring(w http.ResponseWriter, r *http.Request) {
+	var s db.GroupScoring
+	decodeParamVar(r, &s)
+	switch s.Rule {
+	case db.ScoringRank, db.ScoringSolved, db.ScoringNormalized:
+	default:
+		panic(errorx.ErrBadRequest.WithMessage("unknown scoring rule: " + s.Rule))
+	}
+	if s.DropWorst < 0 || s.UpsolveWeight < 0 {
+		panic(errorx.ErrBadRequest.WithMessage("drop_worst and upsolve_weight can't be negative"))
+	}
+	db.UpdGroupScoring(r.Context(), s)
+	msgResponse(w, http.StatusOK, "修改比赛集计分规则成功")
+}
@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+	"zuccacm-server/mq"
+	"zuccacm-server/notify"
+)
+
+const (
+	defaultReminderMinutes = 30
+	defaultResultDelay     = 30
+	// notifications later than it are dropped, e.g. the server was down
+	notifyExpiration = 24 * time.Hour
+	// number of teams in result summary
+	resultSummarySize = 10
+	// time limit of sending a notification by all channels
+	notifySendTimeout = 2 * time.Minute
+)
+
+func init() {
+	c := config.Instance
+	if c.SMTP.Host != "" {
+		notify.Register(notify.NewEmail(c.SMTP.Host, c.SMTP.Port, c.SMTP.User, c.SMTP.Pwd, c.SMTP.From))
+	}
+	if c.WebhookURL != "" {
+		notify.Register(notify.NewWebhook(c.WebhookURL))
+	}
+	if c.BotURL != "" {
+		notify.Register(notify.NewBot(c.BotType, c.BotURL, c.BotGroupId))
+	}
+	mq.Schedule("* * * * *", notifyContests)
+}
+
+// notifyContests is an auto task to send reminders before contests start and results after contests end
+// each kind of notification is sent only once for a contest
+func notifyContests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	now := time.Now()
+	reminder := config.Instance.ReminderMinutes
+	if reminder <= 0 {
+		reminder = defaultReminderMinutes
+	}
+	delay := config.Instance.ResultDelay
+	if delay <= 0 {
+		delay = defaultResultDelay
+	}
+
+	for _, c := range db.GetUnnotifiedContests(ctx, db.NotificationReminder,
+		now, now.Add(time.Duration(reminder)*time.Minute)) {
+		sendContestNotification(ctx, c, db.NotificationReminder)
+	}
+	end := now.Add(-time.Duration(delay) * time.Minute)
+	for _, c := range db.GetUnnotifiedContests(ctx, db.NotificationResult, end.Add(-notifyExpiration), end) {
+		sendContestNotification(ctx, db.GetContestById(ctx, c.Id), db.NotificationResult)
+	}
+}
+
+// sendContestNotification send notification to members of teams in the contest
+// it is marked as sent even if some channels failed, in order not to spam other channels
+func sendContestNotification(ctx context.Context, c db.Contest, kind string) {
+	usernames := make([]string, 0)
+	vis := make(map[string]bool)
+	for _, t := range db.GetTeamsInContest(ctx, c.Id) {
+		for _, u := range t.Users {
+			if !vis[u.Username] {
+				vis[u.Username] = true
+				usernames = append(usernames, u.Username)
+			}
+		}
+	}
+	db.AddContestNotification(ctx, c.Id, kind)
+	if len(usernames) == 0 {
+		return
+	}
+	m := notify.Message{
+		Kind:      kind,
+		ContestId: c.Id,
+		Usernames: usernames,
+		Emails:    db.GetUserEmails(ctx, usernames),
+	}
+	start := time.Time(c.StartTime)
+	switch kind {
+	case db.NotificationReminder:
+		m.Title = fmt.Sprintf("比赛提醒：%s", c.Name)
+		m.Content = fmt.Sprintf("比赛「%s」将于 %s 开始，时长 %d 分钟。",
+			c.Name, start.Format("2006-01-02 15:04"), c.Duration)
+	case db.NotificationResult:
+		m.Title = fmt.Sprintf("比赛结果：%s", c.Name)
+		m.Content = resultSummary(ctx, c)
+	}
+	go sendNotification(m)
+}
+
+// sendNotification send m out of the cron job, so that a slow channel doesn't block the next run
+func sendNotification(m notify.Message) {
+	defer func() {
+		if err := recover(); err != nil {
+			log.WithFields(log.Fields{
+				"contest_id": m.ContestId,
+				"kind":       m.Kind,
+				"error":      err,
+			}).Error("send notification panic")
+		}
+	}()
+	ctx, cancel := context.WithTimeout(context.Background(), notifySendTimeout)
+	defer cancel()
+	if err := notify.Send(ctx, m); err == nil {
+		log.WithFields(log.Fields{
+			"contest_id": m.ContestId,
+			"kind":       m.Kind,
+		}).Info("notification has been sent")
+	}
+}
+
+// resultSummary return top teams of standings (without virtual participations) in text
+func resultSummary(ctx context.Context, c db.Contest) string {
+	s := loadContestStandings(ctx, c)
+	s.filterVirtual()
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("比赛「%s」已结束，共 %d 支队伍参加。\n", c.Name, len(s.Standings)))
+	for i, x := range s.Standings {
+		if i >= resultSummarySize {
+			break
+		}
+		if c.ScoringMode == db.ScoringModeOI {
+			sb.WriteString(fmt.Sprintf("%d. %s  %g 分\n", i+1, x.Team.Name, x.Team.Score))
+		} else {
+			sb.WriteString(fmt.Sprintf("%d. %s  %d 题\n", i+1, x.Team.Name, x.Team.Solved))
+		}
+	}
+	return sb.String()
+}
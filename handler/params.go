@@ -108,6 +108,17 @@ func getParamInt(r *http.Request, key string, defaultValue int) int {
 	return x
 }
 
+func getParamFloat(r *http.Request, key string, defaultValue float64) float64 {
+	if !r.URL.Query().Has(key) {
+		return defaultValue
+	}
+	x, err := strconv.ParseFloat(r.URL.Query().Get(key), 64)
+	if err != nil {
+		panic(errorx.ErrBadRequest.Wrap(err))
+	}
+	return x
+}
+
 func getParamDateInterval(r *http.Request) (begin, end time.Time) {
 	if !r.URL.Query().Has("begin_time") || !r.URL.Query().Has("end_time") {
 		return defaultBeginTime, defaultEndTime
@@ -3,11 +3,100 @@ package handler
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"unicode"
 
 	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
 )
 
+var problemRouter = Router.PathPrefix("/problem").Subrouter()
+
+func init() {
+	Router.HandleFunc("/problems", searchProblems).Methods("GET")
+	problemRouter.HandleFunc("/upsert", upsertProblems).Methods("POST")
+	problemRouter.HandleFunc("/{oj}/{pid}", getProblem).Methods("GET")
+}
+
+// searchProblems filter problems in catalog by oj, keyword (title or pid), tag and difficulty
+func searchProblems(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ojs := db.GetAllOJ(ctx)
+	f := db.ProblemFilter{
+		Keyword:       getParam(r, "keyword", ""),
+		Tag:           getParam(r, "tag", ""),
+		MinDifficulty: getParamInt(r, "min_difficulty", 0),
+		MaxDifficulty: getParamInt(r, "max_difficulty", 0),
+	}
+	if oj := getParam(r, "oj", ""); oj != "" {
+		ojId, ok := db.OJMapStoI(ojs)[oj]
+		if !ok {
+			panic(errorx.ErrBadRequest.WithMessage("oj not found: " + oj))
+		}
+		f.OjId = ojId
+	}
+	problems := db.SearchProblems(ctx, f, decodePage(r))
+	oj := db.OJMapItoS(ojs)
+	for i, p := range problems {
+		problems[i].ProblemURL = getProblemURL(oj[p.OjId], p.Pid)
+	}
+	dataResponse(w, problems)
+}
+
+// getProblem return metadata of the problem, contests using it and users who solved it
+func getProblem(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	ojName := getParamURL(r, "oj")
+	pid := getParamURL(r, "pid")
+	ojId, ok := db.OJMapStoI(db.GetAllOJ(ctx))[ojName]
+	if !ok {
+		panic(errorx.ErrNotFound.New())
+	}
+	p := db.GetProblemMeta(ctx, ojId, pid)
+	if p == nil {
+		panic(errorx.ErrNotFound.New())
+	}
+	p.ProblemURL = getProblemURL(ojName, pid)
+	dataResponse(w, struct {
+		*db.ProblemMeta
+		Contests []db.Contest       `json:"contests"`
+		Solvers  []db.ProblemSolver `json:"solvers"`
+	}{p, db.GetContestsByProblem(ctx, ojId, pid), db.GetProblemSolvers(ctx, ojId, pid)})
+}
+
+// upsertProblems is the callback of spider to update metadata of problems
+func upsertProblems(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		OJ       string `json:"oj"`
+		Problems []struct {
+			Pid        string   `json:"pid"`
+			Title      string   `json:"title"`
+			Difficulty int      `json:"difficulty"`
+			Tags       []string `json:"tags"`
+		} `json:"problems"`
+	}{}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	ojId, ok := db.OJMapStoI(db.GetAllOJ(ctx))[args.OJ]
+	if !ok {
+		panic(errorx.ErrBadRequest.WithMessage("oj not found: " + args.OJ))
+	}
+	problems := make([]db.ProblemMeta, 0)
+	for _, p := range args.Problems {
+		problems = append(problems, db.ProblemMeta{
+			OjId:       ojId,
+			Pid:        p.Pid,
+			Title:      p.Title,
+			Difficulty: p.Difficulty,
+			Tags:       p.Tags,
+		})
+	}
+	db.UpsertProblems(ctx, problems)
+	msgResponse(w, http.StatusOK, "upsert problems success")
+}
+
 func getProblemURL(oj, pid string) string {
 	switch oj {
 	case "codeforces":
@@ -0,0 +1,224 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+var problemListRouter = Router.PathPrefix("/problem_list").Subrouter()
+
+func init() {
+	Router.HandleFunc("/problem_lists", getProblemLists).Methods("GET")
+	problemListRouter.HandleFunc("/add", adminOnly(addProblemList)).Methods("POST")
+	problemListRouter.HandleFunc("/upd", adminOnly(updProblemList)).Methods("POST")
+	problemListRouter.HandleFunc("/del", adminOnly(delProblemList)).Methods("POST")
+	problemListRouter.HandleFunc("/assign", adminOnly(addAssignment)).Methods("POST")
+	problemListRouter.HandleFunc("/del_assignment", adminOnly(delAssignment)).Methods("POST")
+	problemListRouter.HandleFunc("/{id}", getProblemList).Methods("GET")
+	problemListRouter.HandleFunc("/{id}/assignments", getAssignments).Methods("GET")
+	Router.HandleFunc("/assignment/{id}/progress", getAssignmentProgress).Methods("GET")
+	userRouter.HandleFunc("/{username}/assignments", getUserAssignments).Methods("GET")
+}
+
+// assignmentRow is the progress of a user, Problems are in the order of list
+type assignmentRow struct {
+	Username string   `json:"username"`
+	Nickname string   `json:"nickname"`
+	Solved   int      `json:"solved"`
+	Late     int      `json:"late"`
+	Unsolved int      `json:"unsolved"`
+	Problems []string `json:"problems"`
+}
+
+// calcAssignmentProgress return progress of users on problems of the list
+func calcAssignmentProgress(ctx context.Context, l db.ProblemList, deadline db.Datetime, users []db.UserSimple) []assignmentRow {
+	usernames := make([]string, 0)
+	for _, u := range users {
+		usernames = append(usernames, u.Username)
+	}
+	type key struct {
+		username string
+		ojId     int
+		pid      string
+	}
+	solved := make(map[key]time.Time)
+	for _, x := range db.GetFirstAccepted(ctx, usernames, l.Problems) {
+		solved[key{x.Username, x.OjId, x.Pid}] = x.SolvedTime
+	}
+	ret := make([]assignmentRow, 0)
+	for _, u := range users {
+		row := assignmentRow{
+			Username: u.Username,
+			Nickname: u.Nickname,
+			Problems: make([]string, 0),
+		}
+		for _, p := range l.Problems {
+			t, ok := solved[key{u.Username, p.OjId, p.Pid}]
+			switch {
+			case !ok:
+				row.Unsolved++
+				row.Problems = append(row.Problems, db.AssignmentUnsolved)
+			case t.After(time.Time(deadline)):
+				row.Late++
+				row.Problems = append(row.Problems, db.AssignmentLate)
+			default:
+				row.Solved++
+				row.Problems = append(row.Problems, db.AssignmentSolved)
+			}
+		}
+		ret = append(ret, row)
+	}
+	return ret
+}
+
+func loadProblemList(ctx context.Context, id int) db.ProblemList {
+	l := db.GetProblemListById(ctx, id)
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+	for i, p := range l.Problems {
+		l.Problems[i].ProblemURL = getProblemURL(oj[p.OjId], p.Pid)
+	}
+	return l
+}
+
+func getProblemLists(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetProblemLists(r.Context()))
+}
+
+func getProblemList(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, loadProblemList(r.Context(), getParamIntURL(r, "id")))
+}
+
+// checkProblemListItems panic if a problem appears more than once in the list
+func checkProblemListItems(l db.ProblemList) {
+	type key struct {
+		ojId int
+		pid  string
+	}
+	vis := make(map[key]bool)
+	for _, p := range l.Problems {
+		if vis[key{p.OjId, p.Pid}] {
+			panic(errorx.ErrBadRequest.WithMessage(fmt.Sprintf("duplicate problem in list: oj_id=%d, pid=%s", p.OjId, p.Pid)))
+		}
+		vis[key{p.OjId, p.Pid}] = true
+	}
+}
+
+func addProblemList(w http.ResponseWriter, r *http.Request) {
+	var l db.ProblemList
+	decodeParamVar(r, &l)
+	checkProblemListItems(l)
+	l = db.AddProblemList(r.Context(), l)
+	dataResponse(w, struct {
+		Id int `json:"id"`
+	}{l.Id})
+}
+
+func updProblemList(w http.ResponseWriter, r *http.Request) {
+	var l db.ProblemList
+	decodeParamVar(r, &l)
+	if l.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("problem_list.id can't be empty or zero"))
+	}
+	checkProblemListItems(l)
+	db.UpdProblemList(r.Context(), l)
+	msgResponse(w, http.StatusOK, "修改题单成功")
+}
+
+func delProblemList(w http.ResponseWriter, r *http.Request) {
+	args := decodeParam(r.Body)
+	db.DelProblemList(r.Context(), args.getInt("id"))
+	msgResponse(w, http.StatusOK, "删除题单成功")
+}
+
+// addAssignment assign the list to a team group or a user (exactly one of them)
+func addAssignment(w http.ResponseWriter, r *http.Request) {
+	var a db.Assignment
+	decodeParamVar(r, &a)
+	if (a.GroupId == 0) == (a.Username == "") {
+		panic(errorx.ErrBadRequest.WithMessage("exactly one of group_id and username is required"))
+	}
+	if time.Time(a.Deadline).IsZero() {
+		panic(errorx.ErrBadRequest.WithMessage("deadline can't be empty"))
+	}
+	db.AddAssignment(r.Context(), a)
+	msgResponse(w, http.StatusOK, "布置题单成功")
+}
+
+func delAssignment(w http.ResponseWriter, r *http.Request) {
+	args := decodeParam(r.Body)
+	db.DelAssignment(r.Context(), args.getInt("id"))
+	msgResponse(w, http.StatusOK, "删除题单布置成功")
+}
+
+func getAssignments(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetAssignmentsByList(r.Context(), getParamIntURL(r, "id")))
+}
+
+// getUserAssignments return assignments of the user with progress
+func getUserAssignments(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	ctx := r.Context()
+	user := db.MustGetUser(ctx, username)
+	type data struct {
+		db.Assignment
+		Name     string               `json:"name"`
+		Problems []db.ProblemListItem `json:"problems"`
+		Progress assignmentRow        `json:"progress"`
+	}
+	ret := make([]data, 0)
+	for _, a := range db.GetAssignmentsByUser(ctx, username) {
+		l := loadProblemList(ctx, a.ListId)
+		rows := calcAssignmentProgress(ctx, l, a.Deadline, []db.UserSimple{{Username: user.Username, Nickname: user.Nickname}})
+		ret = append(ret, data{a, l.Name, l.Problems, rows[0]})
+	}
+	dataResponse(w, ret)
+}
+
+// getAssignmentProgress return progress matrix of users in the assignment, grouped by official groups
+// users not in any official group are in group 0
+func getAssignmentProgress(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	ctx := r.Context()
+	a := db.GetAssignmentById(ctx, id)
+	l := loadProblemList(ctx, a.ListId)
+	rows := calcAssignmentProgress(ctx, l, a.Deadline, db.GetAssignmentUsers(ctx, a))
+
+	type group struct {
+		GroupId   int             `json:"group_id"`
+		GroupName string          `json:"group_name"`
+		Users     []assignmentRow `json:"users"`
+	}
+	groups := make([]*group, 0)
+	grp := make(map[int]*group)
+	grpOf := make(map[string]int)
+	for _, g := range db.GetOfficialUsers(ctx, true) {
+		grp[g.GroupId] = &group{g.GroupId, g.GroupName, make([]assignmentRow, 0)}
+		groups = append(groups, grp[g.GroupId])
+		for _, u := range g.Users {
+			grpOf[u.Username] = g.GroupId
+		}
+	}
+	grp[0] = &group{0, "其他", make([]assignmentRow, 0)}
+	groups = append(groups, grp[0])
+	for _, row := range rows {
+		g := grp[grpOf[row.Username]]
+		g.Users = append(g.Users, row)
+	}
+	ret := make([]group, 0)
+	for _, g := range groups {
+		if len(g.Users) > 0 {
+			ret = append(ret, *g)
+		}
+	}
+	dataResponse(w, struct {
+		db.Assignment
+		Name     string               `json:"name"`
+		Problems []db.ProblemListItem `json:"problems"`
+		Groups   []group              `json:"groups"`
+	}{a, l.Name, l.Problems, ret})
+}
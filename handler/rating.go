@@ -1,16 +1,101 @@
 package handler
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
 	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/utils"
+	"zuccacm-server/webhook"
 )
 
 var ratingRouter = Router.PathPrefix("/rating").Subrouter()
 
 func init() {
 	ratingRouter.HandleFunc("/upd", updRating).Methods("POST")
+	ratingRouter.HandleFunc("/compare", compareRatings).Methods("GET")
+	userRouter.HandleFunc("/{username}/ratings", getUserRatings).Methods("GET")
+}
+
+// clubOjName is the name of club rating in rating apis
+const clubOjName = "club"
+
+// ratingSeries is the rating history on an oj for charts
+type ratingSeries struct {
+	OjId      int               `json:"oj_id"`
+	OjName    string            `json:"oj_name"`
+	Rating    int               `json:"rating"`
+	MaxRating int               `json:"max_rating"`
+	History   []db.RatingChange `json:"history"`
+}
+
+func getRatingSeries(ctx context.Context, username string, ojId int, ojName string) ratingSeries {
+	x := ratingSeries{
+		OjId:    ojId,
+		OjName:  ojName,
+		History: db.GetRatingHistory(ctx, username, ojId),
+	}
+	for _, y := range x.History {
+		x.Rating = y.Rating
+		x.MaxRating = utils.Max(x.MaxRating, y.Rating)
+	}
+	return x
+}
+
+// getRatingOJ return id of oj by name, club is the club rating
+func getRatingOJ(ctx context.Context, ojName string) int {
+	if ojName == clubOjName {
+		return db.ClubOjId
+	}
+	ojId, ok := db.OJMapStoI(db.GetAllOJ(ctx))[ojName]
+	if !ok {
+		panic(errorx.ErrBadRequest.WithMessage("oj not found: " + ojName))
+	}
+	return ojId
+}
+
+// getUserRatings return rating history on the oj, or on every oj with ratings if oj is not given
+func getUserRatings(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	ctx := r.Context()
+	db.MustGetUser(ctx, username)
+	if ojName := getParam(r, "oj", ""); ojName != "" {
+		dataResponse(w, getRatingSeries(ctx, username, getRatingOJ(ctx, ojName), ojName))
+		return
+	}
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+	oj[db.ClubOjId] = clubOjName
+	data := make([]ratingSeries, 0)
+	for _, ojId := range db.GetRatedOJs(ctx, username) {
+		data = append(data, getRatingSeries(ctx, username, ojId, oj[ojId]))
+	}
+	dataResponse(w, data)
+}
+
+// compareRatings return rating history of users (separated by comma) on the oj (codeforces by default)
+func compareRatings(w http.ResponseWriter, r *http.Request) {
+	ojName := getParam(r, "oj", "codeforces")
+	usernames := strings.Split(getParamRequired(r, "username"), ",")
+	ctx := r.Context()
+	ojId := getRatingOJ(ctx, ojName)
+	type user struct {
+		Username string `json:"username"`
+		Nickname string `json:"nickname"`
+		ratingSeries
+	}
+	data := make([]user, 0)
+	for _, username := range usernames {
+		if username == "" {
+			continue
+		}
+		u := db.MustGetUser(ctx, username)
+		data = append(data, user{u.Username, u.Nickname, getRatingSeries(ctx, username, ojId, ojName)})
+	}
+	dataResponse(w, data)
 }
 
 func updRating(w http.ResponseWriter, r *http.Request) {
@@ -29,11 +114,15 @@ func updRating(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	oj := db.OJMapStoI(db.GetAllEnableOJ(ctx))
-	mp := db.GetAllAccountsMap(ctx)
+	// ratings of unverified accounts are dropped, the previous history of the user is kept
+	mp := db.GetVerifiedAccountsMap(ctx)
 	for _, x := range data {
 		ratings := make([]db.Rating, 0)
 		ojId := oj[x.OJ]
-		username := mp[db.Account{OjId: ojId, Account: x.Username}]
+		username, ok := mp[db.Account{OjId: ojId, Account: x.Username}]
+		if !ok {
+			continue
+		}
 		for _, y := range x.Ratings {
 			ratings = append(ratings, db.Rating{
 				OjId:        ojId,
@@ -45,7 +134,14 @@ func updRating(w http.ResponseWriter, r *http.Request) {
 				ContestURL:  y.ContestURL,
 			})
 		}
-		db.UpdRating(ctx, username, ojId, ratings)
+		if db.UpdRating(ctx, username, ojId, ratings) {
+			webhook.Emit(webhook.RatingUpdated, map[string]interface{}{
+				"username": username,
+				"oj":       x.OJ,
+				"ratings":  ratings,
+			})
+			pushNotification(ctx, []string{username}, db.InboxTask, fmt.Sprintf("你的 %s rating 已更新", x.OJ))
+		}
 	}
 	msgResponse(w, http.StatusOK, "upd user rating success")
 }
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"net/http"
+	"sort"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+const (
+	// rating of users who have not taken part in any rated contest
+	defaultRecommendRating = 800
+	// default window is [rating+low, rating+high]
+	defaultRecommendLow  = 0
+	defaultRecommendHigh = 300
+	defaultRecommendSize = 20
+	// weakness of tags never attempted, between solved all (0) and solved none (1)
+	unknownTagWeakness = 0.5
+)
+
+func init() {
+	userRouter.HandleFunc("/{username}/recommendations", getRecommendations).Methods("GET")
+}
+
+type recommendation struct {
+	db.ProblemMeta
+	Weakness float64 `json:"weakness"`
+	Reason   string  `json:"reason"`
+}
+
+// getRecommendations suggest codeforces problems in catalog within the rating window above the user's rating
+// problems solved by the user or teammates are excluded
+// problems with tags of low acceptance rate (weakness = 1 - success rate) are preferred
+func getRecommendations(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	low := getParamInt(r, "low", defaultRecommendLow)
+	high := getParamInt(r, "high", defaultRecommendHigh)
+	size := getParamInt(r, "size", defaultRecommendSize)
+	if low > high || size <= 0 {
+		panic(errorx.ErrBadRequest.WithMessage("invalid window or size"))
+	}
+	ctx := r.Context()
+	db.MustGetUser(ctx, username)
+	ojs := db.GetAllOJ(ctx)
+	ojName := "codeforces"
+	cf := db.OJMapStoI(ojs)[ojName]
+
+	rating := db.GetRating(ctx, username, cf)
+	if rating <= 0 {
+		rating = defaultRecommendRating
+	}
+	solved := make(map[string]bool)
+	users := append(db.GetTeammates(ctx, username), username)
+	for _, p := range db.GetSolvedProblems(ctx, users) {
+		if p.OjId == cf {
+			solved[p.Pid] = true
+		}
+	}
+	weakness := make(map[string]float64)
+	attempts := db.GetProblemAttempts(ctx, []string{username}, defaultBeginTime, defaultEndTime)
+	for _, x := range calcSkills(attempts, defaultDifficultyBand).Tags {
+		weakness[x.Name] = 1 - x.SuccessRate
+	}
+
+	candidates := db.SearchProblems(ctx, db.ProblemFilter{
+		OjId:          cf,
+		MinDifficulty: rating + low,
+		MaxDifficulty: rating + high,
+	}, db.Page{})
+	ret := make([]recommendation, 0)
+	for _, p := range candidates {
+		if solved[p.Pid] || p.Difficulty == 0 {
+			continue
+		}
+		x := recommendation{ProblemMeta: p}
+		for _, t := range p.Tags {
+			wk, ok := weakness[t]
+			if !ok {
+				wk = unknownTagWeakness
+			}
+			if wk > x.Weakness || x.Reason == "" {
+				x.Weakness, x.Reason = wk, t
+			}
+		}
+		x.ProblemURL = getProblemURL(ojName, p.Pid)
+		ret = append(ret, x)
+	}
+	// newer problems (larger contest id) first among the same weakness and difficulty
+	sort.SliceStable(ret, func(i, j int) bool {
+		if ret[i].Weakness != ret[j].Weakness {
+			return ret[i].Weakness > ret[j].Weakness
+		}
+		if ret[i].Difficulty != ret[j].Difficulty {
+			return ret[i].Difficulty < ret[j].Difficulty
+		}
+		return len(ret[i].Pid) > len(ret[j].Pid) || (len(ret[i].Pid) == len(ret[j].Pid) && ret[i].Pid > ret[j].Pid)
+	})
+	if len(ret) > size {
+		ret = ret[:size]
+	}
+	dataResponse(w, struct {
+		Rating          int              `json:"rating"`
+		Recommendations []recommendation `json:"recommendations"`
+	}{rating, ret})
+}
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+var contestScheduleRouter = Router.PathPrefix("/contest_schedule").Subrouter()
+
+func init() {
+	Router.HandleFunc("/contest_schedules", adminOnly(getContestSchedules)).Methods("GET")
+	contestScheduleRouter.HandleFunc("/add", adminOnly(addContestSchedule)).Methods("POST")
+	contestScheduleRouter.HandleFunc("/upd", adminOnly(updContestSchedule)).Methods("POST")
+	contestScheduleRouter.HandleFunc("/del", adminOnly(delContestSchedule)).Methods("POST")
+}
+
+// checkContestSchedule panic if spec is not a valid cron spec or fields are missing
+func checkContestSchedule(s db.ContestSchedule) {
+	if _, err := cron.ParseStandard(s.Spec); err != nil {
+		panic(errorx.ErrBadRequest.WithMessage("invalid spec: " + err.Error()))
+	}
+	if s.Duration <= 0 || s.GroupId == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("schedule.duration and schedule.group_id are required"))
+	}
+	if s.LeadDays <= 0 {
+		panic(errorx.ErrBadRequest.WithMessage("schedule.lead_days must be positive"))
+	}
+	checkScoringMode(db.Contest{ScoringMode: s.ScoringMode})
+}
+
+func getContestSchedules(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetContestSchedules(r.Context(), false))
+}
+
+func addContestSchedule(w http.ResponseWriter, r *http.Request) {
+	s := db.ContestSchedule{LeadDays: 7, IsEnable: true}
+	decodeParamVar(r, &s)
+	checkContestSchedule(s)
+	if s.Teams == nil {
+		s.Teams = make([]int, 0)
+	}
+	// contests are created from now on
+	s.LastTime = db.Datetime(time.Now())
+	db.AddContestSchedule(r.Context(), s)
+	msgResponse(w, http.StatusOK, "添加比赛计划成功")
+}
+
+func updContestSchedule(w http.ResponseWriter, r *http.Request) {
+	var s db.ContestSchedule
+	decodeParamVar(r, &s)
+	if s.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("schedule.id can't be empty or zero"))
+	}
+	checkContestSchedule(s)
+	if s.Teams == nil {
+		s.Teams = make([]int, 0)
+	}
+	db.UpdContestSchedule(r.Context(), s)
+	msgResponse(w, http.StatusOK, "修改比赛计划成功")
+}
+
+func delContestSchedule(w http.ResponseWriter, r *http.Request) {
+	args := decodeParam(r.Body)
+	db.DelContestSchedule(r.Context(), args.getInt("id"))
+	msgResponse(w, http.StatusOK, "删除比赛计划成功")
+}
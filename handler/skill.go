@@ -0,0 +1,147 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+// defaultDifficultyBand is the width of difficulty bands, e.g. [1200, 1400)
+const defaultDifficultyBand = 200
+
+func init() {
+	userRouter.HandleFunc("/{username}/skills", getUserSkills).Methods("GET")
+	Router.HandleFunc("/team_group/{id}/skills", getGroupSkills).Methods("GET")
+}
+
+// skillItem is the summary of problems of a tag or a difficulty band
+// SuccessRate = Solved / Attempted
+type skillItem struct {
+	Name        string  `json:"name"`
+	Solved      int     `json:"solved"`
+	Attempted   int     `json:"attempted"`
+	Submissions int     `json:"submissions"`
+	SuccessRate float64 `json:"success_rate"`
+}
+
+type skills struct {
+	Tags         []skillItem `json:"tags"`
+	Difficulties []skillItem `json:"difficulties"`
+}
+
+// calcSkills summarize attempts by tag and by difficulty band, problems without difficulty are not in any band
+// tags are ordered by attempted problems, and bands by difficulty
+func calcSkills(attempts []db.ProblemAttempt, band int) skills {
+	tags := make(map[string]*skillItem)
+	bands := make(map[int]*skillItem)
+	add := func(item *skillItem, a db.ProblemAttempt) {
+		item.Attempted++
+		item.Submissions += a.Submissions
+		if a.IsSolved {
+			item.Solved++
+		}
+	}
+	for _, a := range attempts {
+		for _, t := range a.Tags {
+			if tags[t] == nil {
+				tags[t] = &skillItem{Name: t}
+			}
+			add(tags[t], a)
+		}
+		if a.Difficulty > 0 {
+			b := a.Difficulty / band * band
+			if bands[b] == nil {
+				bands[b] = &skillItem{Name: fmt.Sprintf("%d-%d", b, b+band-1)}
+			}
+			add(bands[b], a)
+		}
+	}
+
+	ret := skills{
+		Tags:         make([]skillItem, 0),
+		Difficulties: make([]skillItem, 0),
+	}
+	for _, x := range tags {
+		x.SuccessRate = float64(x.Solved) / float64(x.Attempted)
+		ret.Tags = append(ret.Tags, *x)
+	}
+	sort.SliceStable(ret.Tags, func(i, j int) bool {
+		if ret.Tags[i].Attempted != ret.Tags[j].Attempted {
+			return ret.Tags[i].Attempted > ret.Tags[j].Attempted
+		}
+		return ret.Tags[i].Name < ret.Tags[j].Name
+	})
+	keys := make([]int, 0)
+	for b := range bands {
+		keys = append(keys, b)
+	}
+	sort.Ints(keys)
+	for _, b := range keys {
+		x := bands[b]
+		x.SuccessRate = float64(x.Solved) / float64(x.Attempted)
+		ret.Difficulties = append(ret.Difficulties, *x)
+	}
+	return ret
+}
+
+func getDifficultyBand(r *http.Request) int {
+	band := getParamInt(r, "band", defaultDifficultyBand)
+	if band <= 0 {
+		panic(errorx.ErrBadRequest.WithMessage("band must be positive"))
+	}
+	return band
+}
+
+// getUserSkills return solved counts and success rates per tag and per difficulty band
+// only submissions during [begin_time, end_time] on problems in catalog are counted
+func getUserSkills(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	begin, end := getParamDateInterval(r)
+	band := getDifficultyBand(r)
+	attempts := db.GetProblemAttempts(r.Context(), []string{username}, begin, end)
+	dataResponse(w, calcSkills(attempts, band))
+}
+
+// getGroupSkills return skills of each member of an official team group, to compare members
+func getGroupSkills(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	begin, end := getParamDateInterval(r)
+	band := getDifficultyBand(r)
+	ctx := r.Context()
+	var users []db.User
+	found := false
+	for _, g := range db.GetOfficialUsers(ctx, true) {
+		if g.GroupId == id {
+			users, found = g.Users, true
+		}
+	}
+	if !found {
+		panic(errorx.ErrNotFound.New())
+	}
+	usernames := make([]string, 0)
+	for _, u := range users {
+		usernames = append(usernames, u.Username)
+	}
+	attempts := make(map[string][]db.ProblemAttempt)
+	for _, a := range db.GetProblemAttempts(ctx, usernames, begin, end) {
+		attempts[a.Username] = append(attempts[a.Username], a)
+	}
+	type member struct {
+		Username string `json:"username"`
+		Nickname string `json:"nickname"`
+		skills
+	}
+	members := make([]member, 0)
+	all := make([]db.ProblemAttempt, 0)
+	for _, u := range users {
+		members = append(members, member{u.Username, u.Nickname, calcSkills(attempts[u.Username], band)})
+		all = append(all, attempts[u.Username]...)
+	}
+	dataResponse(w, struct {
+		Group   skills   `json:"group"`
+		Members []member `json:"members"`
+	}{calcSkills(all, band), members})
+}
@@ -1,21 +1,53 @@
 package handler
 
 import (
+	"context"
+	"encoding/json"
 	"sort"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
 
 	"zuccacm-server/db"
+	"zuccacm-server/enum/verdict"
+	"zuccacm-server/mq"
 )
 
+func init() {
+	mq.Schedule("*/10 * * * *", snapshotEndedContests)
+}
+
 const defaultAcceptedTime = -1000000000
 
+// fullScore is the score of an accepted submission in OI contest
+const fullScore = 100
+
+// penaltyTime is the penalty in minutes of each rejected submission of a solved problem
+const penaltyTime = 20
+
 type submissionInfo struct {
 	IsAccepted bool        `json:"is_accepted"`
+	Score      *float64    `json:"score,omitempty"`
+	Verdict    string      `json:"verdict,omitempty"`
 	CreateTime db.Datetime `json:"create_time"`
 }
 
+// score return 100 for accepted submission without score
+func (s submissionInfo) score() float64 {
+	if s.Score != nil {
+		return *s.Score
+	}
+	if s.IsAccepted {
+		return fullScore
+	}
+	return 0
+}
+
 type problemResult struct {
 	AcceptedTime int              `json:"accepted_time"`
 	Dirt         int              `json:"dirt"`
+	Score        float64          `json:"score"`
 	Submissions  []submissionInfo `json:"submissions"`
 }
 
@@ -40,7 +72,18 @@ func maxResult(x, y problemResult) problemResult {
 	}
 }
 
-// calcProblemResult return results of a problem
+// maxOIResult compare score first
+func maxOIResult(x, y problemResult) problemResult {
+	if x.Score != y.Score {
+		if x.Score < y.Score {
+			return y
+		}
+		return x
+	}
+	return maxResult(x, y)
+}
+
+// calcProblemResult return results of a problem, compilation errors are not counted in dirt
 // problemResult.AcceptedTime as follows:
 // unsolved --- -1
 // solved   --- [0, duration]
@@ -60,7 +103,7 @@ func calcProblemResult(submissions []submissionInfo, startTime db.Datetime, dura
 		}
 		if s.IsAccepted {
 			ret.AcceptedTime = int((s.CreateTime.Unix() - startTime.Unix()) / 60)
-		} else {
+		} else if s.Verdict != verdict.CE {
 			ret.Dirt++
 		}
 	}
@@ -71,3 +114,349 @@ func calcProblemResult(submissions []submissionInfo, startTime db.Datetime, dura
 	}
 	return ret
 }
+
+// calcOIProblemResult return results of a problem in OI contest
+// problemResult.Score is the best (or last) score during contest, full score is regarded as accepted
+func calcOIProblemResult(submissions []submissionInfo, startTime db.Datetime, duration int, scoreRule string) problemResult {
+	ret := calcProblemResult(submissions, startTime, duration)
+	begin := startTime.Unix()
+	end := begin + int64(duration)*60
+	if ret.AcceptedTime == -1 {
+		dirt := 0
+		for _, s := range ret.Submissions {
+			if s.score() >= fullScore {
+				ret.AcceptedTime, ret.Dirt = int((s.CreateTime.Unix()-begin)/60), dirt
+				if ret.AcceptedTime < 0 || ret.AcceptedTime > duration {
+					ret.AcceptedTime = duration + 1
+				}
+				break
+			}
+			if s.Verdict != verdict.CE {
+				dirt++
+			}
+		}
+	}
+	for _, s := range ret.Submissions {
+		if s.CreateTime.Unix() < begin || s.CreateTime.Unix() > end {
+			continue
+		}
+		if scoreRule == db.ScoreRuleLast || s.score() > ret.Score {
+			ret.Score = s.score()
+		}
+	}
+	return ret
+}
+
+// calcContestProblemResult return results of a problem by scoring mode of the contest
+func calcContestProblemResult(submissions []submissionInfo, c db.Contest) problemResult {
+	if c.ScoringMode == db.ScoringModeOI {
+		return calcOIProblemResult(submissions, c.StartTime, c.Duration, c.ScoreRule)
+	}
+	return calcProblemResult(submissions, c.StartTime, c.Duration)
+}
+
+type standingRow struct {
+	Id             string          `json:"id"`
+	Name           string          `json:"name"`
+	Solved         int             `json:"solved"`
+	Score          float64         `json:"score"`
+	ProblemResults []problemResult `json:"problem_results"`
+}
+
+// sum update Solved and Score by ProblemResults
+func (row *standingRow) sum() {
+	for _, pr := range row.ProblemResults {
+		if pr.AcceptedTime != -1 {
+			row.Solved++
+		}
+		row.Score += pr.Score
+	}
+}
+
+// standing of a virtual participation has only team row with username as id
+type standing struct {
+	Team      standingRow   `json:"team"`
+	Users     []standingRow `json:"users"`
+	IsVirtual bool          `json:"is_virtual"`
+}
+
+type contestStandings struct {
+	Contest   db.Contest `json:"contest"`
+	Standings []standing `json:"standings"`
+}
+
+type standingKey struct {
+	Username string
+	OjId     int
+	Pid      string
+}
+
+// submissions return submissions of each user kept in standings
+// self_team keeps them in team row, normal_team keeps them in user rows
+func (s *contestStandings) submissions() map[standingKey][]submissionInfo {
+	ret := make(map[standingKey][]submissionInfo)
+	add := func(row standingRow) {
+		for i, pr := range row.ProblemResults {
+			if i >= len(s.Contest.Problems) || len(pr.Submissions) == 0 {
+				continue
+			}
+			p := s.Contest.Problems[i]
+			key := standingKey{row.Id, p.OjId, p.Pid}
+			ret[key] = append(ret[key], pr.Submissions...)
+		}
+	}
+	for _, x := range s.Standings {
+		if x.Users == nil {
+			add(x.Team)
+		}
+		for _, u := range x.Users {
+			add(u)
+		}
+	}
+	return ret
+}
+
+// mergeSubmissions return union of x and y, submissions with same time, result and score are regarded as the same
+func mergeSubmissions(x, y []submissionInfo) []submissionInfo {
+	type key struct {
+		createTime int64
+		isAccepted bool
+		score      float64
+	}
+	vis := make(map[key]bool)
+	ret := make([]submissionInfo, 0, len(x)+len(y))
+	for _, s := range append(append([]submissionInfo{}, x...), y...) {
+		k := key{s.CreateTime.Unix(), s.IsAccepted, s.score()}
+		if vis[k] {
+			continue
+		}
+		vis[k] = true
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// calcContestStandings calc standings from submissions in db
+// submissions in history will be merged, so that submissions deleted from db are not lost
+func calcContestStandings(ctx context.Context, contest db.Contest, history map[standingKey][]submissionInfo) contestStandings {
+	var data contestStandings
+	id := contest.Id
+
+	sub := db.GetSubmissionsInContest(ctx, id)
+	sub = append(sub, db.GetVirtualSubmissionsInContest(ctx, id)...)
+	mpSub := make(map[standingKey][]submissionInfo)
+	for _, s := range sub {
+		key := standingKey{s.Username, s.OjId, s.Pid}
+		mpSub[key] = append(mpSub[key], submissionInfo{s.IsAccepted, s.Score, s.Verdict, s.CreateTime})
+	}
+	for key, s := range history {
+		mpSub[key] = mergeSubmissions(mpSub[key], s)
+	}
+	data.Standings = make([]standing, 0)
+	merge := maxResult
+	if contest.ScoringMode == db.ScoringModeOI {
+		merge = maxOIResult
+	}
+
+	// members who take part virtually are moved out of their teams
+	virtuals := db.GetVirtualsByContest(ctx, id)
+	isVirtual := make(map[string]bool)
+	for _, v := range virtuals {
+		isVirtual[v.Username] = true
+	}
+	teams := db.GetTeamsInContest(ctx, id)
+	for _, t := range teams {
+		users := make([]db.UserSimple, 0)
+		for _, u := range t.Users {
+			if !isVirtual[u.Username] {
+				users = append(users, u)
+			}
+		}
+		if len(users) == 0 && len(t.Users) > 0 {
+			continue
+		}
+		t.Users = users
+		x := standing{
+			Team: standingRow{
+				Id:             strconv.Itoa(t.Id),
+				Name:           t.Name,
+				ProblemResults: make([]problemResult, len(contest.Problems)),
+			},
+			Users: make([]standingRow, 0),
+		}
+		// init team Row
+		for i := range contest.Problems {
+			x.Team.ProblemResults[i] = calcProblemResult(nil, contest.StartTime, contest.Duration)
+		}
+		for _, u := range t.Users {
+			uRow := standingRow{
+				Id:             u.Username,
+				Name:           u.Nickname,
+				ProblemResults: make([]problemResult, len(contest.Problems)),
+			}
+			for i, p := range contest.Problems {
+				key := standingKey{u.Username, p.OjId, p.Pid}
+				uRow.ProblemResults[i] = calcContestProblemResult(mpSub[key], contest)
+			}
+			uRow.sum()
+			// upd team Row
+			for i, pr := range uRow.ProblemResults {
+				x.Team.ProblemResults[i] = merge(x.Team.ProblemResults[i], pr)
+			}
+			x.Users = append(x.Users, uRow)
+		}
+		x.Team.sum()
+		// self_team should have no user, normal_team should have no submission
+		if t.IsSelf {
+			x.Team.Id = t.Users[0].Username
+			x.Team.Name = t.Users[0].Nickname
+			x.Users = nil
+		} else {
+			for i := range x.Team.ProblemResults {
+				x.Team.ProblemResults[i].Submissions = nil
+			}
+		}
+		data.Standings = append(data.Standings, x)
+	}
+	// virtual participants are evaluated against their own start time
+	for _, v := range virtuals {
+		vc := contest
+		vc.StartTime = v.StartTime
+		x := standing{
+			Team: standingRow{
+				Id:             v.Username,
+				Name:           v.Nickname,
+				ProblemResults: make([]problemResult, len(contest.Problems)),
+			},
+			IsVirtual: true,
+		}
+		for i, p := range contest.Problems {
+			key := standingKey{v.Username, p.OjId, p.Pid}
+			x.Team.ProblemResults[i] = calcContestProblemResult(mpSub[key], vc)
+		}
+		x.Team.sum()
+		data.Standings = append(data.Standings, x)
+	}
+	sort.SliceStable(data.Standings, func(i, j int) bool {
+		x, y := data.Standings[i].Team, data.Standings[j].Team
+		if contest.ScoringMode == db.ScoringModeOI && x.Score != y.Score {
+			return x.Score > y.Score
+		}
+		if x.Solved == y.Solved {
+			return x.Name < y.Name
+		}
+		return x.Solved > y.Solved
+	})
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+	for i, p := range contest.Problems {
+		contest.Problems[i].ProblemURL = getProblemURL(oj[p.OjId], p.Pid)
+	}
+	data.Contest = contest
+	return data
+}
+
+func isContestEnded(c db.Contest) bool {
+	end := time.Time(c.StartTime).Add(time.Duration(c.Duration) * time.Minute)
+	return end.Before(time.Now())
+}
+
+// saveStandingSnapshot recalc and store standings of a finished contest
+// submissions kept in the old snapshot will be merged unless rebuild=true
+func saveStandingSnapshot(ctx context.Context, contest db.Contest, rebuild bool) (contestStandings, time.Time) {
+	var history map[standingKey][]submissionInfo
+	if !rebuild {
+		if old := db.GetStandingSnapshot(ctx, contest.Id); old != nil {
+			var s contestStandings
+			if err := json.Unmarshal([]byte(old.Data), &s); err != nil {
+				panic(err)
+			}
+			history = s.submissions()
+		}
+	}
+	data := calcContestStandings(ctx, contest, history)
+	b, err := json.Marshal(data)
+	if err != nil {
+		panic(err)
+	}
+	now := time.Now()
+	db.UpdStandingSnapshot(ctx, db.StandingSnapshot{
+		ContestId:  contest.Id,
+		Data:       string(b),
+		CreateTime: now,
+	})
+	return data, now
+}
+
+// loadStandingSnapshot return the snapshot of a finished contest, create it if not exists
+func loadStandingSnapshot(ctx context.Context, contest db.Contest) (contestStandings, time.Time) {
+	s := db.GetStandingSnapshot(ctx, contest.Id)
+	if s == nil {
+		return saveStandingSnapshot(ctx, contest, true)
+	}
+	var data contestStandings
+	if err := json.Unmarshal([]byte(s.Data), &data); err != nil {
+		panic(err)
+	}
+	return data, s.CreateTime
+}
+
+// peekContestStandings is loadContestStandings without creating snapshot, for read-only usage
+func peekContestStandings(ctx context.Context, contest db.Contest) contestStandings {
+	if isContestEnded(contest) {
+		if s := db.GetStandingSnapshot(ctx, contest.Id); s != nil {
+			var data contestStandings
+			if err := json.Unmarshal([]byte(s.Data), &data); err != nil {
+				panic(err)
+			}
+			return data
+		}
+	}
+	return calcContestStandings(ctx, contest, nil)
+}
+
+// loadContestStandings return snapshot for finished contests and live standings for others
+func loadContestStandings(ctx context.Context, contest db.Contest) contestStandings {
+	if !isContestEnded(contest) {
+		return calcContestStandings(ctx, contest, nil)
+	}
+	data, _ := loadStandingSnapshot(ctx, contest)
+	return data
+}
+
+// filterVirtual remove standings of virtual participations
+func (s *contestStandings) filterVirtual() {
+	ret := make([]standing, 0)
+	for _, x := range s.Standings {
+		if !x.IsVirtual {
+			ret = append(ret, x)
+		}
+	}
+	s.Standings = ret
+}
+
+// refreshStandingSnapshots merge new submissions into existing snapshots
+// snapshot of a contest which is not finished any more will be deleted
+func refreshStandingSnapshots(ctx context.Context, contestIds []int) {
+	for _, id := range contestIds {
+		if db.GetStandingSnapshot(ctx, id) == nil {
+			continue
+		}
+		contest := db.GetContestById(ctx, id)
+		if isContestEnded(contest) {
+			saveStandingSnapshot(ctx, contest, false)
+		} else {
+			db.DelStandingSnapshot(ctx, id)
+		}
+	}
+}
+
+// snapshotEndedContests is an auto task to snapshot contests which have just ended
+func snapshotEndedContests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*30)
+	defer cancel()
+	contests := db.GetEndedContestsWithoutSnapshot(ctx, time.Now(), 20)
+	for _, c := range contests {
+		saveStandingSnapshot(ctx, db.GetContestById(ctx, c.Id), true)
+		log.WithField("contest_id", c.Id).Info("standing snapshot has been created")
+	}
+}
@@ -1,12 +1,20 @@
 package handler
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"net/http"
+	"sync"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 
 	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/enum/verdict"
 	"zuccacm-server/mq"
+	"zuccacm-server/webhook"
 )
 
 var submissionRouter = Router.PathPrefix("/submission").Subrouter()
@@ -15,6 +23,7 @@ func init() {
 	submissionRouter.HandleFunc("/add", addSubmissions).Methods("POST")
 	submissionRouter.HandleFunc("/refresh_all", adminOnly(refreshAllSubmission)).Methods("POST")
 	submissionRouter.HandleFunc("/refresh", userSelfOrAdminOnly(refreshSubmission)).Methods("POST")
+	submissionRouter.HandleFunc("/task_done", submissionTaskDone).Methods("POST")
 
 	Router.HandleFunc("/overview", submissionOverview).Methods("GET")
 }
@@ -27,6 +36,11 @@ func addSubmissions(w http.ResponseWriter, r *http.Request) {
 		Sid        string      `json:"sid"`
 		Pid        string      `json:"pid"`
 		IsAccepted bool        `json:"is_accepted"`
+		Score      *float64    `json:"score"`
+		Verdict    string      `json:"verdict"`
+		Language   string      `json:"language"`
+		RunTime    int         `json:"run_time"`
+		Memory     int         `json:"memory"`
 		CreateTime db.Datetime `json:"create_time"`
 	}
 	args := struct {
@@ -43,18 +57,61 @@ func addSubmissions(w http.ResponseWriter, r *http.Request) {
 	oj := db.OJMapStoI(db.GetAllOJ(ctx))
 	data := make([]db.Submission, 0)
 	for _, s := range args.Submissions {
+		v := parseVerdict(s.Verdict, s.IsAccepted)
 		data = append(data, db.Submission{
 			Username:    s.Username,
 			OjId:        oj[s.OJ],
 			AccountOjId: args.AccountOjId,
 			Sid:         s.Sid,
 			Pid:         s.Pid,
-			IsAccepted:  s.IsAccepted,
+			IsAccepted:  s.IsAccepted || v == verdict.OK,
+			Score:       s.Score,
+			Verdict:     v,
+			Language:    s.Language,
+			RunTime:     s.RunTime,
+			Memory:      s.Memory,
 			CreateTime:  s.CreateTime,
 		})
 	}
 	log.Debug(data[0])
-	db.AddSubmission(ctx, data)
+	// accepted submissions which exist before are not emitted again
+	accepted := make(map[int][]string)
+	for _, s := range data {
+		if s.IsAccepted {
+			accepted[s.OjId] = append(accepted[s.OjId], s.Sid)
+		}
+	}
+	type key struct {
+		ojId int
+		sid  string
+	}
+	existing := make(map[key]bool)
+	for ojId, sids := range accepted {
+		for _, sid := range db.GetExistingSids(ctx, ojId, sids) {
+			existing[key{ojId, sid}] = true
+		}
+	}
+	if db.AddSubmission(ctx, data) > 0 {
+		// merge new submissions into snapshots of finished contests
+		pids := make(map[int][]string)
+		for _, s := range data {
+			pids[s.OjId] = append(pids[s.OjId], s.Pid)
+		}
+		for ojId, x := range pids {
+			refreshStandingSnapshots(ctx, db.GetSnapshotContestsByPid(ctx, ojId, x))
+		}
+		// submissions are posted by account of the oj, only those of verified accounts are added
+		accounts := db.GetVerifiedAccountsMap(ctx)
+		events := make([]interface{}, 0)
+		for _, s := range data {
+			username, ok := accounts[db.Account{OjId: s.AccountOjId, Account: s.Username}]
+			if ok && s.IsAccepted && !existing[key{s.OjId, s.Sid}] {
+				s.Account, s.Username = s.Username, username
+				events = append(events, s)
+			}
+		}
+		webhook.EmitAll(webhook.SubmissionAccepted, events)
+	}
 	msgResponse(w, http.StatusOK, "add submissions success")
 }
 
@@ -85,12 +142,82 @@ func refreshSubmission(w http.ResponseWriter, r *http.Request) {
 	args.Count = 1e9
 	decodeParamVar(r, &args)
 	account := db.GetAccount(r.Context(), args.Username, args.OjId)
-	mq.ExecTask(mq.Topic(args.OjId), mq.SubmissionTask([]string{account}, args.Count, nil, 0))
+	execUserSubmissionTask(args.Username, args.OjId, account, args.Count)
 	msgResponse(w, http.StatusOK, "任务已创建：刷新提交")
 }
 
+// userTaskTTL is how long a crawling task of a user is waited for
+const userTaskTTL = 24 * time.Hour
+
+type userTask struct {
+	username   string
+	ojId       int
+	createTime time.Time
+}
+
+// userTasks are crawling tasks triggered by users, the user is notified once when the task is finished
+var userTasks = struct {
+	mu sync.Mutex
+	m  map[string]userTask
+}{m: make(map[string]userTask)}
+
+// execUserSubmissionTask crawl the latest count submissions of the account for the user
+func execUserSubmissionTask(username string, ojId int, account string, count int) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	id := hex.EncodeToString(b)
+	now := time.Now()
+	userTasks.mu.Lock()
+	for k, x := range userTasks.m {
+		if now.Sub(x.createTime) > userTaskTTL {
+			delete(userTasks.m, k)
+		}
+	}
+	userTasks.m[id] = userTask{username, ojId, now}
+	userTasks.mu.Unlock()
+	mq.ExecTask(mq.Topic(ojId), mq.SubmissionTask([]string{account}, count, nil, 0).WithId(id))
+}
+
+// submissionTaskDone is the callback of spider when a submission task with id is finished
+func submissionTaskDone(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		TaskId string `json:"task_id"`
+	}{}
+	decodeParamVar(r, &args)
+	userTasks.mu.Lock()
+	t, ok := userTasks.m[args.TaskId]
+	delete(userTasks.m, args.TaskId)
+	userTasks.mu.Unlock()
+	if !ok {
+		panic(errorx.ErrNotFound.WithMessage("task not found"))
+	}
+	ctx := r.Context()
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+	pushNotification(ctx, []string{t.username}, db.InboxTask, fmt.Sprintf("%s 提交记录同步完成", oj[t.ojId]))
+	msgResponse(w, http.StatusOK, "task done")
+}
+
 func submissionOverview(w http.ResponseWriter, r *http.Request) {
 	begin, end := getParamDateInterval(r)
 	data := db.GetOverview(r.Context(), begin, end)
 	dataResponse(w, data)
 }
+
+// parseVerdict return verdict.OK for accepted submission without verdict
+// unknown verdicts are regarded as verdict.OT
+func parseVerdict(s string, isAccepted bool) string {
+	if s == "" {
+		if isAccepted {
+			return verdict.OK
+		}
+		return ""
+	}
+	v, err := verdict.Parse(s)
+	if err != nil {
+		log.WithField("verdict", s).Warn(err)
+		return verdict.OT
+	}
+	return v
+}
@@ -1,7 +1,10 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
+	"strconv"
+
 	"zuccacm-server/db"
 )
 
@@ -15,6 +18,7 @@ func init() {
 
 	teamRouter.HandleFunc("/add", adminOnly(addTeam)).Methods("POST")
 	teamRouter.HandleFunc("/upd_enable", adminOnly(updTeamEnable)).Methods("POST")
+	teamRouter.HandleFunc("/upd_users", adminOnly(updTeamUsers)).Methods("POST")
 }
 func getTeam(w http.ResponseWriter, r *http.Request) {
 	teamId := getParamURL(r, "team_id")
@@ -54,6 +58,7 @@ func addTeam(w http.ResponseWriter, r *http.Request) {
 		team.Users = append(team.Users, db.UserSimple{Username: s})
 	}
 	db.AddTeam(r.Context(), team)
+	pushNotification(r.Context(), args.Users, db.InboxTeam, fmt.Sprintf("你已加入队伍 %s", args.Name))
 	msgResponse(w, http.StatusOK, "添加队伍成功")
 }
 func addTeamGroup(w http.ResponseWriter, r *http.Request) {
@@ -75,6 +80,33 @@ func addTeamGroup(w http.ResponseWriter, r *http.Request) {
 func updTeamEnable(w http.ResponseWriter, r *http.Request) {
 	var team db.Team
 	decodeParamVar(r, &team)
-	db.UpdTeamEnable(r.Context(), team)
+	ctx := r.Context()
+	db.UpdTeamEnable(ctx, team)
+	if t, err := db.GetTeam(ctx, strconv.Itoa(team.Id)); err == nil {
+		content := fmt.Sprintf("你的队伍 %s 已停用", t.Name)
+		if team.IsEnable {
+			content = fmt.Sprintf("你的队伍 %s 已启用", t.Name)
+		}
+		pushTeamNotification(ctx, team.Id, db.InboxTeam, content)
+	}
 	msgResponse(w, http.StatusOK, "修改队伍状态成功")
 }
+
+// updTeamUsers replace members of the team, added and removed users are notified
+func updTeamUsers(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		TeamId int      `json:"team_id"`
+		Users  []string `json:"users"`
+	}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	team, err := db.GetTeam(ctx, strconv.Itoa(args.TeamId))
+	if err != nil {
+		msgResponse(w, http.StatusBadRequest, "队伍不存在")
+		return
+	}
+	added, removed := db.UpdTeamUsers(ctx, args.TeamId, args.Users)
+	pushNotification(ctx, added, db.InboxTeam, fmt.Sprintf("你已加入队伍 %s", team.Name))
+	pushNotification(ctx, removed, db.InboxTeam, fmt.Sprintf("你已离开队伍 %s", team.Name))
+	msgResponse(w, http.StatusOK, "修改队伍成员成功")
+}
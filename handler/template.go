@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+var contestTemplateRouter = Router.PathPrefix("/contest_template").Subrouter()
+
+func init() {
+	Router.HandleFunc("/contest_templates", adminOnly(getContestTemplates)).Methods("GET")
+	contestTemplateRouter.HandleFunc("/add", adminOnly(addContestTemplate)).Methods("POST")
+	contestTemplateRouter.HandleFunc("/upd", adminOnly(updContestTemplate)).Methods("POST")
+	contestTemplateRouter.HandleFunc("/del", adminOnly(delContestTemplate)).Methods("POST")
+	contestTemplateRouter.HandleFunc("/{id}", adminOnly(getContestTemplate)).Methods("GET")
+}
+
+func getContestTemplates(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetContestTemplates(r.Context()))
+}
+
+func getContestTemplate(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	dataResponse(w, db.GetContestTemplateById(r.Context(), id))
+}
+
+func addContestTemplate(w http.ResponseWriter, r *http.Request) {
+	var t db.ContestTemplate
+	decodeParamVar(r, &t)
+	checkScoringMode(db.Contest{ScoringMode: t.ScoringMode})
+	db.AddContestTemplate(r.Context(), t)
+	msgResponse(w, http.StatusOK, "添加比赛模板成功")
+}
+
+func updContestTemplate(w http.ResponseWriter, r *http.Request) {
+	var t db.ContestTemplate
+	decodeParamVar(r, &t)
+	if t.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("template.id can't be empty or zero"))
+	}
+	checkScoringMode(db.Contest{ScoringMode: t.ScoringMode})
+	db.UpdContestTemplate(r.Context(), t)
+	msgResponse(w, http.StatusOK, "修改比赛模板成功")
+}
+
+func delContestTemplate(w http.ResponseWriter, r *http.Request) {
+	args := decodeParam(r.Body)
+	db.DelContestTemplate(r.Context(), args.getInt("id"))
+	msgResponse(w, http.StatusOK, "删除比赛模板成功")
+}
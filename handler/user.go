@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"sort"
 	"time"
@@ -27,6 +28,7 @@ func init() {
 	userRouter.HandleFunc("/{username}", getUser).Methods("GET")
 	userRouter.HandleFunc("/{username}/profile", userSelfOrAdminOnly(getUserProfile)).Methods("GET")
 	userRouter.HandleFunc("/{username}/accounts", getUserAccounts).Methods("GET")
+	userRouter.HandleFunc("/{username}/account_history", adminOnly(getUserAccountHistory)).Methods("GET")
 	userRouter.HandleFunc("/{username}/submissions", getUserSubmissions).Methods("GET")
 	userRouter.HandleFunc("/{username}/contests", getUserContests).Methods("GET")
 	userRouter.HandleFunc("/{username}/groups", getGroupsByUser).Methods("GET")
@@ -57,10 +59,19 @@ func updUser(w http.ResponseWriter, r *http.Request) {
 	msgResponse(w, http.StatusOK, "修改用户信息成功")
 }
 
+// updUserAccount bind a new account, submissions of the previous account are kept
+// the new account is unverified, its history is crawled after it is verified
 func updUserAccount(w http.ResponseWriter, r *http.Request) {
 	var account db.Account
 	decodeParamVar(r, &account)
-	db.UpdAccount(r.Context(), account)
+	ctx := r.Context()
+	if !db.UpdAccount(ctx, account) {
+		msgResponse(w, http.StatusOK, "账号未变化")
+		return
+	}
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
+	pushNotification(ctx, []string{account.Username}, db.InboxAccount,
+		fmt.Sprintf("你的 %s 账号已修改为 %s，请完成账号验证", oj[account.OjId], account.Account))
 	msgResponse(w, http.StatusOK, "修改用户账号成功")
 }
 
@@ -156,9 +167,10 @@ func getUserProfile(w http.ResponseWriter, r *http.Request) {
 
 func getUserAccounts(w http.ResponseWriter, r *http.Request) {
 	type account struct {
-		OjId    int    `json:"oj_id" db:"oj_id"`
-		OjName  string `json:"oj_name" db:"oj_name"`
-		Account string `json:"account" db:"account"`
+		OjId       int    `json:"oj_id" db:"oj_id"`
+		OjName     string `json:"oj_name" db:"oj_name"`
+		Account    string `json:"account" db:"account"`
+		IsVerified bool   `json:"is_verified" db:"is_verified"`
 	}
 	ctx := r.Context()
 	username := getParamURL(r, "username")
@@ -176,6 +188,7 @@ func getUserAccounts(w http.ResponseWriter, r *http.Request) {
 	ac := db.GetAccountsByUsername(ctx, username)
 	for _, x := range ac {
 		data[mp[x.OjId]].Account = x.Account
+		data[mp[x.OjId]].IsVerified = x.IsVerified
 	}
 	dataResponse(w, data)
 }
@@ -215,14 +228,14 @@ func getUserSubmissions(w http.ResponseWriter, r *http.Request) {
 		i := utils.SubDays(begin, time.Time(s.CreateTime))
 		data[i].Submission++
 	}
+	oj := db.OJMapItoS(db.GetAllOJ(ctx))
 	submissions = db.GetAcceptedSubmissionByUsername(ctx, username, begin, end)
 	for _, s := range submissions {
 		i := utils.SubDays(begin, time.Time(s.CreateTime))
 		data[i].Solved++
-		oj := db.GetOJById(s.OjId)
 		data[i].Solves = append(data[i].Solves, RT{
 			Solve:  s,
-			OjName: oj.OjName,
+			OjName: oj[s.OjId],
 		})
 	}
 	dataResponse(w, data)
@@ -234,7 +247,10 @@ func getUserContests(w http.ResponseWriter, r *http.Request) {
 		ContestName    string          `json:"contest_name"`
 		StartTime      db.Datetime     `json:"start_time"`
 		Duration       int             `json:"duration"`
+		ScoringMode    string          `json:"scoring_mode"`
+		IsVirtual      bool            `json:"is_virtual"`
 		Solved         int             `json:"solved"`
+		Score          float64         `json:"score"`
 		Problems       []db.Problem    `json:"problems"`
 		ProblemResults []problemResult `json:"problem_results"`
 	}
@@ -246,13 +262,36 @@ func getUserContests(w http.ResponseWriter, r *http.Request) {
 	username := getParamURL(r, "username")
 	begin, end := getParamDateInterval(r)
 	groupId := getParamInt(r, "group_id", 0)
+	showVirtual := getParamBool(r, "show_virtual", true)
 	contests := db.GetContestsByUser(ctx, username, begin, end, groupId)
-	for _, c := range contests {
+	// submissions are evaluated against virtual start time if the user has registered one
+	virtual := make(map[int]db.Datetime)
+	if showVirtual {
+		virtual = db.GetVirtualsByUser(ctx, username)
+		contests = append(contests, db.GetVirtualContestsByUser(ctx, username, begin, end, groupId)...)
+		sort.SliceStable(contests, func(i, j int) bool {
+			x, y := contests[i].StartTime, contests[j].StartTime
+			if t, ok := virtual[contests[i].Id]; ok {
+				x = t
+			}
+			if t, ok := virtual[contests[j].Id]; ok {
+				y = t
+			}
+			return x.Unix() > y.Unix()
+		})
+	}
+	for i, c := range contests {
+		t, isVirtual := virtual[c.Id]
+		if isVirtual {
+			contests[i].StartTime = t
+		}
 		data.Contests = append(data.Contests, Row{
 			ContestId:      c.Id,
 			ContestName:    c.Name,
-			StartTime:      c.StartTime,
+			StartTime:      contests[i].StartTime,
 			Duration:       c.Duration,
+			ScoringMode:    c.ScoringMode,
+			IsVirtual:      isVirtual,
 			Problems:       c.Problems,
 			ProblemResults: make([]problemResult, len(c.Problems)),
 		})
@@ -266,14 +305,15 @@ func getUserContests(w http.ResponseWriter, r *http.Request) {
 	mp := make(map[Key][]submissionInfo)
 	for _, s := range submissions {
 		key := Key{s.OjId, s.Pid}
-		mp[key] = append(mp[key], submissionInfo{s.IsAccepted, s.CreateTime})
+		mp[key] = append(mp[key], submissionInfo{s.IsAccepted, s.Score, s.Verdict, s.CreateTime})
 	}
-	for i, c := range data.Contests {
+	for i, c := range contests {
 		for j, p := range c.Problems {
-			data.Contests[i].ProblemResults[j] = calcProblemResult(mp[Key{p.OjId, p.Pid}], c.StartTime, c.Duration)
+			data.Contests[i].ProblemResults[j] = calcContestProblemResult(mp[Key{p.OjId, p.Pid}], c)
 			if data.Contests[i].ProblemResults[j].AcceptedTime != -1 {
 				data.Contests[i].Solved++
 			}
+			data.Contests[i].Score += data.Contests[i].ProblemResults[j].Score
 		}
 	}
 	dataResponse(w, data)
@@ -339,12 +379,16 @@ func getUsers(w http.ResponseWriter, r *http.Request) {
 // official users are those who are in team_groups with is_grade=true
 func getMembers(w http.ResponseWriter, r *http.Request) {
 	type user struct {
-		Username    string   `json:"username"`
-		Nickname    string   `json:"nickname"`
-		CfRating    int      `json:"cf_rating"`
-		CfMaxRating int      `json:"cf_max_rating"`
-		Awards      []string `json:"awards"`
-		Medals      [3]int   `json:"medals"`
+		Username     string   `json:"username"`
+		Nickname     string   `json:"nickname"`
+		CfRating     int      `json:"cf_rating"`
+		CfMaxRating  int      `json:"cf_max_rating"`
+		AtcRating    int      `json:"atc_rating,omitempty"`
+		AtcMaxRating int      `json:"atc_max_rating,omitempty"`
+		NcRating     int      `json:"nc_rating,omitempty"`
+		NcMaxRating  int      `json:"nc_max_rating,omitempty"`
+		Awards       []string `json:"awards"`
+		Medals       [3]int   `json:"medals"`
 	}
 	type group struct {
 		GroupId   int    `json:"group_id"`
@@ -355,18 +399,28 @@ func getMembers(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
 	oj := db.OJMapStoI(db.GetAllOJ(ctx))
-	ratings := db.GetOfficialUserRatings(ctx, oj["codeforces"])
 	type rating struct {
 		rating    int
 		maxRating int
 	}
-	cf := make(map[string]rating)
-	for _, x := range ratings {
-		cf[x.Username] = rating{
-			rating:    x.Rating,
-			maxRating: x.MaxRating,
+	// ratings of official users on the oj, empty if the oj does not exist
+	getRatings := func(ojName string) map[string]rating {
+		ret := make(map[string]rating)
+		ojId, ok := oj[ojName]
+		if !ok {
+			return ret
 		}
+		for _, x := range db.GetOfficialUserRatings(ctx, ojId) {
+			ret[x.Username] = rating{
+				rating:    x.Rating,
+				maxRating: x.MaxRating,
+			}
+		}
+		return ret
 	}
+	cf := getRatings("codeforces")
+	atc := getRatings("atcoder")
+	nc := getRatings("nowcoder")
 
 	mpUser := make(map[string]*user)
 	mpGroup := make(map[int]*group)
@@ -379,11 +433,15 @@ func getMembers(w http.ResponseWriter, r *http.Request) {
 		}
 		for _, u := range x.Users {
 			mpUser[u.Username] = &user{
-				Username:    u.Username,
-				Nickname:    u.Nickname,
-				CfRating:    cf[u.Username].rating,
-				CfMaxRating: cf[u.Username].maxRating,
-				Awards:      make([]string, 0),
+				Username:     u.Username,
+				Nickname:     u.Nickname,
+				CfRating:     cf[u.Username].rating,
+				CfMaxRating:  cf[u.Username].maxRating,
+				AtcRating:    atc[u.Username].rating,
+				AtcMaxRating: atc[u.Username].maxRating,
+				NcRating:     nc[u.Username].rating,
+				NcMaxRating:  nc[u.Username].maxRating,
+				Awards:       make([]string, 0),
 			}
 		}
 	}
@@ -422,3 +480,11 @@ func getMembers(w http.ResponseWriter, r *http.Request) {
 	}
 	dataResponse(w, data)
 }
+
+// getUserAccountHistory return all accounts the user has bound with validity periods
+func getUserAccountHistory(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	ctx := r.Context()
+	db.MustGetUser(ctx, username)
+	dataResponse(w, db.GetAccountHistory(ctx, username))
+}
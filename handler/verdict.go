@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+)
+
+// verdict of old submissions which only have is_accepted
+const unknownVerdict = "UNKNOWN"
+
+func init() {
+	userRouter.HandleFunc("/{username}/verdicts", getUserVerdicts).Methods("GET")
+	contestRouter.HandleFunc("/{id}/verdicts", getContestVerdicts).Methods("GET")
+}
+
+type verdictDistribution struct {
+	Total     int            `json:"total"`
+	Verdicts  map[string]int `json:"verdicts"`
+	Languages map[string]int `json:"languages"`
+}
+
+func newVerdictDistribution() *verdictDistribution {
+	return &verdictDistribution{
+		Verdicts:  make(map[string]int),
+		Languages: make(map[string]int),
+	}
+}
+
+func (d *verdictDistribution) add(s db.Submission) {
+	d.Total++
+	v := s.Verdict
+	if v == "" {
+		v = unknownVerdict
+	}
+	d.Verdicts[v]++
+	if s.Language != "" {
+		d.Languages[s.Language]++
+	}
+}
+
+// getUserVerdicts return distribution of verdicts and languages of the user during [begin_time, end_time]
+func getUserVerdicts(w http.ResponseWriter, r *http.Request) {
+	username := getParamURL(r, "username")
+	begin, end := getParamDateInterval(r)
+	d := newVerdictDistribution()
+	for _, s := range db.GetSubmissionByUsername(r.Context(), username, begin, end) {
+		d.add(s)
+	}
+	dataResponse(w, d)
+}
+
+// getContestVerdicts return distribution of verdicts and languages of submissions during the contest
+// in total and per problem, submissions of virtual participants are not included
+func getContestVerdicts(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	ctx := r.Context()
+	contest := db.GetContestById(ctx, id)
+	begin := time.Time(contest.StartTime)
+	end := begin.Add(time.Duration(contest.Duration) * time.Minute)
+
+	type problemKey struct {
+		OjId int
+		Pid  string
+	}
+	total := newVerdictDistribution()
+	problems := make([]*verdictDistribution, len(contest.Problems))
+	idx := make(map[problemKey]int)
+	for i, p := range contest.Problems {
+		problems[i] = newVerdictDistribution()
+		idx[problemKey{p.OjId, p.Pid}] = i
+	}
+	for _, s := range db.GetSubmissionsInContest(ctx, id) {
+		t := time.Time(s.CreateTime)
+		if t.Before(begin) || t.After(end) {
+			continue
+		}
+		total.add(s)
+		if i, ok := idx[problemKey{s.OjId, s.Pid}]; ok {
+			problems[i].add(s)
+		}
+	}
+	type problem struct {
+		Index string `json:"index"`
+		*verdictDistribution
+	}
+	data := struct {
+		*verdictDistribution
+		Problems []problem `json:"problems"`
+	}{total, make([]problem, 0)}
+	for i, p := range contest.Problems {
+		data.Problems = append(data.Problems, problem{p.Index, problems[i]})
+	}
+	dataResponse(w, data)
+}
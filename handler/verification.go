@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	mrand "math/rand"
+	"net/http"
+	"time"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/mq"
+)
+
+// challengeTTL is how long a challenge can be checked after it is created
+const challengeTTL = 2 * time.Hour
+
+func init() {
+	userRouter.HandleFunc("/oj_challenge", userSelfOrAdminOnly(addAccountChallenge)).Methods("POST")
+	userRouter.HandleFunc("/verify_oj", userSelfOrAdminOnly(verifyAccount)).Methods("POST")
+	userRouter.HandleFunc("/oj_verified", accountVerified).Methods("POST")
+	userRouter.HandleFunc("/upd_oj_verified", adminOnly(updAccountVerified)).Methods("POST")
+}
+
+// crawlVerifiedAccount fetch the full history of the account which is just verified
+func crawlVerifiedAccount(username string, ojId int, account string) {
+	execUserSubmissionTask(username, ojId, account, 1e9)
+}
+
+func findAccount(r *http.Request, username string, ojId int) db.Account {
+	for _, x := range db.GetAccountsByUsername(r.Context(), username) {
+		if x.OjId == ojId && x.Account != "" {
+			return x
+		}
+	}
+	panic(errorx.ErrBadRequest.WithMessage("account not bound"))
+}
+
+// addAccountChallenge issue a challenge for the current account of the user
+// profile: set token as organization or first name of the profile
+// compile_error: submit a compile error to pid
+func addAccountChallenge(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Username string `json:"username"`
+		OjId     int    `json:"oj_id"`
+		Method   string `json:"method"`
+	}{Method: db.ChallengeProfile}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	account := findAccount(r, args.Username, args.OjId)
+	if account.IsVerified {
+		panic(errorx.ErrBadRequest.WithMessage("account is already verified"))
+	}
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	c := db.AccountChallenge{
+		Username:   args.Username,
+		OjId:       args.OjId,
+		Account:    account.Account,
+		Method:     args.Method,
+		Token:      "zuccacm-" + hex.EncodeToString(b),
+		CreateTime: db.Datetime(time.Now()),
+	}
+	switch args.Method {
+	case db.ChallengeProfile:
+	case db.ChallengeCompileError:
+		problems := db.SearchProblems(ctx, db.ProblemFilter{OjId: args.OjId}, db.Page{})
+		if len(problems) == 0 {
+			panic(errorx.ErrBadRequest.WithMessage("no problem of the oj in catalog"))
+		}
+		c.Pid = problems[mrand.Intn(len(problems))].Pid
+	default:
+		panic(errorx.ErrBadRequest.WithMessage("unknown method: " + args.Method))
+	}
+	db.SetAccountChallenge(ctx, c)
+	dataResponse(w, c)
+}
+
+// verifyAccount ask spider to check the pending challenge
+func verifyAccount(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Username string `json:"username"`
+		OjId     int    `json:"oj_id"`
+	}{}
+	decodeParamVar(r, &args)
+	c := db.GetAccountChallenge(r.Context(), args.Username, args.OjId)
+	if time.Since(time.Time(c.CreateTime)) > challengeTTL {
+		panic(errorx.ErrBadRequest.WithMessage("challenge is expired, please create a new one"))
+	}
+	mq.ExecTask(mq.Topic(c.OjId), mq.VerifyTask(c.Account, c.Method, c.Token, c.Pid, c.CreateTime))
+	msgResponse(w, http.StatusOK, "任务已创建：验证账号")
+}
+
+// spiderSignature return hex HMAC-SHA256 of "oj:account:token:is_verified" keyed with Secret.SpiderKey
+func spiderSignature(key, oj, account, token string, isVerified bool) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(fmt.Sprintf("%s:%s:%s:%t", oj, account, token, isVerified)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// accountVerified is the callback of spider with result of the challenge
+// the token is visible to the user, so the callback must be signed by spider
+func accountVerified(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		OJ         string `json:"oj"`
+		Account    string `json:"account"`
+		Token      string `json:"token"`
+		IsVerified bool   `json:"is_verified"`
+		Signature  string `json:"signature"`
+	}{}
+	decodeParamVar(r, &args)
+	key := config.Instance.SpiderKey
+	if key == "" {
+		panic(errorx.ErrForbidden.WithMessage("spider key is not configured"))
+	}
+	sign := spiderSignature(key, args.OJ, args.Account, args.Token, args.IsVerified)
+	if !hmac.Equal([]byte(sign), []byte(args.Signature)) {
+		panic(errorx.ErrForbidden.WithMessage("invalid signature"))
+	}
+	ctx := r.Context()
+	ojId, ok := db.OJMapStoI(db.GetAllOJ(ctx))[args.OJ]
+	if !ok {
+		panic(errorx.ErrBadRequest.WithMessage("oj not found: " + args.OJ))
+	}
+	c, ok := db.GetAccountChallengeByToken(ctx, ojId, args.Account, args.Token)
+	if !ok {
+		panic(errorx.ErrNotFound.WithMessage("challenge not found"))
+	}
+	if args.IsVerified && time.Since(time.Time(c.CreateTime)) <= challengeTTL {
+		db.UpdAccountVerified(ctx, c.Username, ojId, true)
+		crawlVerifiedAccount(c.Username, ojId, c.Account)
+		pushNotification(ctx, []string{c.Username}, db.InboxAccount,
+			fmt.Sprintf("你的 %s 账号 %s 已通过验证", args.OJ, c.Account))
+	} else {
+		pushNotification(ctx, []string{c.Username}, db.InboxAccount,
+			fmt.Sprintf("你的 %s 账号 %s 未通过验证", args.OJ, c.Account))
+	}
+	msgResponse(w, http.StatusOK, "upd account verification success")
+}
+
+func updAccountVerified(w http.ResponseWriter, r *http.Request) {
+	args := struct {
+		Username   string `json:"username"`
+		OjId       int    `json:"oj_id"`
+		IsVerified bool   `json:"is_verified"`
+	}{}
+	decodeParamVar(r, &args)
+	account := findAccount(r, args.Username, args.OjId)
+	db.UpdAccountVerified(r.Context(), args.Username, args.OjId, args.IsVerified)
+	if args.IsVerified && !account.IsVerified {
+		crawlVerifiedAccount(args.Username, args.OjId, account.Account)
+	}
+	msgResponse(w, http.StatusOK, "修改账号验证状态成功")
+}
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"net/http"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+)
+
+func init() {
+	contestRouter.HandleFunc("/virtual", userSelfOrAdminOnly(addVirtual)).Methods("POST")
+	contestRouter.HandleFunc("/del_virtual", userSelfOrAdminOnly(delVirtual)).Methods("POST")
+	contestRouter.HandleFunc("/{id}/virtuals", getVirtuals).Methods("GET")
+}
+
+// addVirtual register a virtual start time of a finished contest for the user
+// the start time is between the end of contest and now
+// users in teams of the contest can register too, they are moved out of their teams in standings
+func addVirtual(w http.ResponseWriter, r *http.Request) {
+	var v db.VirtualParticipation
+	decodeParamVar(r, &v)
+	ctx := r.Context()
+	contest := db.GetContestById(ctx, v.ContestId)
+	if !isContestEnded(contest) {
+		panic(errorx.ErrBadRequest.WithMessage("contest has not ended yet"))
+	}
+	end := time.Time(contest.StartTime).Add(time.Duration(contest.Duration) * time.Minute)
+	if t := time.Time(v.StartTime); t.Before(end) || t.After(time.Now()) {
+		panic(errorx.ErrBadRequest.WithMessage("virtual start time must be after the contest ends and not in the future"))
+	}
+	db.MustGetUser(ctx, v.Username)
+	db.UpdVirtual(ctx, v)
+	refreshStandingSnapshots(ctx, []int{v.ContestId})
+	msgResponse(w, http.StatusOK, "报名虚拟参赛成功")
+}
+
+func delVirtual(w http.ResponseWriter, r *http.Request) {
+	var args struct {
+		ContestId int    `json:"contest_id"`
+		Username  string `json:"username"`
+	}
+	decodeParamVar(r, &args)
+	ctx := r.Context()
+	db.DelVirtual(ctx, args.ContestId, args.Username)
+	refreshStandingSnapshots(ctx, []int{args.ContestId})
+	msgResponse(w, http.StatusOK, "取消虚拟参赛成功")
+}
+
+func getVirtuals(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	dataResponse(w, db.GetVirtualsByContest(r.Context(), id))
+}
@@ -0,0 +1,86 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/webhook"
+)
+
+var webhookRouter = Router.PathPrefix("/webhook").Subrouter()
+
+var webhookEvents = map[string]bool{
+	"*":                        true,
+	webhook.SubmissionAccepted: true,
+	webhook.ContestCreated:     true,
+	webhook.RatingUpdated:      true,
+	webhook.AwardAdded:         true,
+}
+
+func init() {
+	Router.HandleFunc("/webhooks", adminOnly(getWebhooks)).Methods("GET")
+	webhookRouter.HandleFunc("/add", adminOnly(addWebhook)).Methods("POST")
+	webhookRouter.HandleFunc("/upd", adminOnly(updWebhook)).Methods("POST")
+	webhookRouter.HandleFunc("/del", adminOnly(delWebhook)).Methods("POST")
+	webhookRouter.HandleFunc("/{id}/deliveries", adminOnly(getWebhookDeliveries)).Methods("GET")
+}
+
+// checkWebhook panic if url or events are invalid, and generate secret if empty
+func checkWebhook(h *db.Webhook) {
+	if u, err := url.Parse(h.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		panic(errorx.ErrBadRequest.WithMessage("invalid url: " + h.URL))
+	}
+	if h.Events == nil {
+		h.Events = make([]string, 0)
+	}
+	for _, e := range h.Events {
+		if !webhookEvents[e] {
+			panic(errorx.ErrBadRequest.WithMessage("unknown event: " + e))
+		}
+	}
+	if h.Secret == "" {
+		b := make([]byte, 20)
+		if _, err := rand.Read(b); err != nil {
+			panic(err)
+		}
+		h.Secret = hex.EncodeToString(b)
+	}
+}
+
+func getWebhooks(w http.ResponseWriter, r *http.Request) {
+	dataResponse(w, db.GetWebhooks(r.Context(), false))
+}
+
+func addWebhook(w http.ResponseWriter, r *http.Request) {
+	h := db.Webhook{IsEnable: true}
+	decodeParamVar(r, &h)
+	checkWebhook(&h)
+	db.AddWebhook(r.Context(), h)
+	msgResponse(w, http.StatusOK, "添加 webhook 成功")
+}
+
+func updWebhook(w http.ResponseWriter, r *http.Request) {
+	var h db.Webhook
+	decodeParamVar(r, &h)
+	if h.Id == 0 {
+		panic(errorx.ErrBadRequest.WithMessage("webhook.id can't be empty or zero"))
+	}
+	checkWebhook(&h)
+	db.UpdWebhook(r.Context(), h)
+	msgResponse(w, http.StatusOK, "修改 webhook 成功")
+}
+
+func delWebhook(w http.ResponseWriter, r *http.Request) {
+	args := decodeParam(r.Body)
+	db.DelWebhook(r.Context(), args.getInt("id"))
+	msgResponse(w, http.StatusOK, "删除 webhook 成功")
+}
+
+func getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := getParamIntURL(r, "id")
+	dataResponse(w, db.GetWebhookDeliveries(r.Context(), id, decodePage(r)))
+}
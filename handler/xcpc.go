@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 	"zuccacm-server/db"
+	"zuccacm-server/enum/errorx"
+	"zuccacm-server/webhook"
 )
 
 var xcpcRouter = Router.PathPrefix("/xcpc").Subrouter()
@@ -16,6 +19,7 @@ func init() {
 	Router.HandleFunc("/xcpc_team_rels", adminOnly(getXcpcTeamRels)).Methods("GET")
 	xcpcRouter.HandleFunc("/add", adminOnly(addXcpc)).Methods("POST")
 	xcpc_team_relRouter.HandleFunc("/add", adminOnly(addXcpcTeamRel)).Methods("POST")
+	xcpc_team_relRouter.HandleFunc("/upd_award", adminOnly(updXcpcTeamAward)).Methods("POST")
 }
 func getXcpc(w http.ResponseWriter, r *http.Request) {
 	xcpcId := getParamURL(r, "xcpc_id")
@@ -103,6 +107,27 @@ func addXcpcTeamRel(w http.ResponseWriter, r *http.Request) {
 		Medal:  0,
 		Award:  "",
 	}
-	db.AddXcpcTeamRel(r.Context(), xcpc_team_rel)
+	ctx := r.Context()
+	db.AddXcpcTeamRel(ctx, xcpc_team_rel)
+	if xcpc, err := db.GetXcpc(ctx, args.XcpcId); err == nil {
+		pushTeamNotification(ctx, tid, db.InboxAward, fmt.Sprintf("你的队伍已登记参加 %s", xcpc.Name))
+	}
 	msgResponse(w, http.StatusOK, "增加参赛队伍成功")
 }
+
+// updXcpcTeamAward set the award of a team in xcpc, members are notified only if it is changed
+func updXcpcTeamAward(w http.ResponseWriter, r *http.Request) {
+	var args db.XcpcTeamRel
+	decodeParamVar(r, &args)
+	if args.Medal < 0 {
+		panic(errorx.ErrBadRequest.WithMessage("medal can't be negative"))
+	}
+	ctx := r.Context()
+	if db.UpdXcpcTeamAward(ctx, args) {
+		webhook.Emit(webhook.AwardAdded, args)
+		if xcpc, err := db.GetXcpc(ctx, strconv.Itoa(args.XcpcId)); err == nil && args.Award != "" {
+			pushTeamNotification(ctx, args.TeamId, db.InboxAward, fmt.Sprintf("你的队伍在 %s 获得 %s", xcpc.Name, args.Award))
+		}
+	}
+	msgResponse(w, http.StatusOK, "修改获奖信息成功")
+}
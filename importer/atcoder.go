@@ -0,0 +1,83 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"zuccacm-server/db"
+)
+
+type atcoderStandings struct {
+	TaskInfo []struct {
+		Assignment     string `json:"Assignment"`
+		TaskScreenName string `json:"TaskScreenName"`
+	} `json:"TaskInfo"`
+	StandingsData []struct {
+		UserScreenName string `json:"UserScreenName"`
+		TaskResults    map[string]struct {
+			Failure int   `json:"Failure"`
+			Penalty int   `json:"Penalty"`
+			Score   int   `json:"Score"`
+			Elapsed int64 `json:"Elapsed"`
+		} `json:"TaskResults"`
+	} `json:"StandingsData"`
+}
+
+// parseAtCoder parse the standings json, which has no name, start time or duration of contest
+// so Options.StartTime and Options.Duration are required
+func parseAtCoder(data []byte, opt Options) (*contestData, error) {
+	var s atcoderStandings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if len(s.TaskInfo) == 0 {
+		return nil, errors.New("atcoder standings has no task")
+	}
+	// TaskScreenName is like 'abc300_a'
+	cid := s.TaskInfo[0].TaskScreenName
+	if i := strings.LastIndex(cid, "_"); i > 0 {
+		cid = cid[:i]
+	}
+	ret := &contestData{
+		OJ: FormatAtCoder,
+		Contest: db.Contest{
+			Cid:      cid,
+			Name:     cid,
+			Problems: make([]db.Problem, 0),
+		},
+		Submissions: make([]db.Submission, 0),
+	}
+	for _, t := range s.TaskInfo {
+		ret.Contest.Problems = append(ret.Contest.Problems, db.Problem{
+			Pid:   t.TaskScreenName,
+			Index: t.Assignment,
+		})
+	}
+	if !opt.WithSubmissions {
+		return ret, nil
+	}
+	if opt.StartTime.IsZero() || opt.Duration <= 0 {
+		return nil, errors.New("start time and duration are required by atcoder standings")
+	}
+	start := opt.StartTime
+	end := start.Add(time.Duration(opt.Duration) * time.Minute)
+	for _, row := range s.StandingsData {
+		for pid, tr := range row.TaskResults {
+			var acceptedAt time.Time
+			var score *float64
+			rejected := tr.Failure
+			// Score is 100 times of points, Elapsed is in nanoseconds
+			if tr.Score > 0 {
+				acceptedAt = start.Add(time.Duration(tr.Elapsed))
+				points := float64(tr.Score) / 100
+				score = &points
+				rejected = tr.Penalty
+			}
+			ret.Submissions = append(ret.Submissions,
+				synthesize(cid, row.UserScreenName, pid, rejected, acceptedAt, end, score)...)
+		}
+	}
+	return ret, nil
+}
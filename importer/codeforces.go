@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"zuccacm-server/db"
+)
+
+type cfStandings struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+	Result  struct {
+		Contest struct {
+			Id               int    `json:"id"`
+			Name             string `json:"name"`
+			Type             string `json:"type"`
+			DurationSeconds  int64  `json:"durationSeconds"`
+			StartTimeSeconds int64  `json:"startTimeSeconds"`
+		} `json:"contest"`
+		Problems []struct {
+			ContestId int     `json:"contestId"`
+			Index     string  `json:"index"`
+			Points    float64 `json:"points"`
+		} `json:"problems"`
+		Rows []struct {
+			Party struct {
+				Members []struct {
+					Handle string `json:"handle"`
+				} `json:"members"`
+				ParticipantType string `json:"participantType"`
+			} `json:"party"`
+			ProblemResults []struct {
+				Points                    float64 `json:"points"`
+				RejectedAttemptCount      int     `json:"rejectedAttemptCount"`
+				BestSubmissionTimeSeconds int64   `json:"bestSubmissionTimeSeconds"`
+			} `json:"problemResults"`
+		} `json:"rows"`
+	} `json:"result"`
+}
+
+// cfFullScore is the score of a full solve, scores of IOI rounds are normalized to it (as fullScore in standings)
+const cfFullScore = 100
+
+// parseCodeforces parse the response of contest.standings
+// only rows of official contestants are used to synthesize submissions
+// in IOI rounds a problem is accepted only if it gets full points, otherwise the partial score is kept
+// by a rejected submission, points of other rounds are not scores so positive points only mean accepted
+func parseCodeforces(data []byte, opt Options) (*contestData, error) {
+	var s cfStandings
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	if s.Status != "OK" {
+		return nil, errors.New("codeforces response is not OK: " + s.Comment)
+	}
+	c := s.Result.Contest
+	cid := strconv.Itoa(c.Id)
+	start := time.Unix(c.StartTimeSeconds, 0)
+	ret := &contestData{
+		OJ: FormatCodeforces,
+		Contest: db.Contest{
+			Cid:       cid,
+			Name:      c.Name,
+			StartTime: db.Datetime(start),
+			Duration:  int(c.DurationSeconds / 60),
+			Problems:  make([]db.Problem, 0),
+		},
+		Submissions: make([]db.Submission, 0),
+	}
+	if c.StartTimeSeconds == 0 {
+		ret.Contest.StartTime = db.Datetime{}
+	}
+	for _, p := range s.Result.Problems {
+		ret.Contest.Problems = append(ret.Contest.Problems, db.Problem{
+			Pid:   fmt.Sprintf("%d%s", p.ContestId, p.Index),
+			Index: p.Index,
+		})
+	}
+	if !opt.WithSubmissions {
+		return ret, nil
+	}
+	end := start.Add(time.Duration(c.DurationSeconds) * time.Second)
+	for _, row := range s.Result.Rows {
+		if row.Party.ParticipantType != "CONTESTANT" {
+			continue
+		}
+		for i, pr := range row.ProblemResults {
+			if i >= len(ret.Contest.Problems) {
+				break
+			}
+			bestAt := start.Add(time.Duration(pr.BestSubmissionTimeSeconds) * time.Second)
+			var acceptedAt time.Time
+			var score *float64
+			partial := false
+			if c.Type == "IOI" {
+				if full := s.Result.Problems[i].Points; full > 0 && pr.Points > 0 {
+					x := pr.Points / full * cfFullScore
+					if x >= cfFullScore {
+						x = cfFullScore
+						acceptedAt = bestAt
+					} else {
+						partial = true
+					}
+					score = &x
+				}
+			} else if pr.Points > 0 {
+				acceptedAt = bestAt
+			}
+			pid := ret.Contest.Problems[i].Pid
+			for _, m := range row.Party.Members {
+				ret.Submissions = append(ret.Submissions,
+					synthesize(cid, m.Handle, pid, pr.RejectedAttemptCount, acceptedAt, end, score)...)
+				if partial {
+					ret.Submissions = append(ret.Submissions, db.Submission{
+						Username:   m.Handle,
+						Sid:        synthesizeSid(cid, m.Handle, pid, pr.RejectedAttemptCount),
+						Pid:        pid,
+						Score:      score,
+						CreateTime: db.Datetime(bestAt),
+					})
+				}
+			}
+		}
+	}
+	return ret, nil
+}
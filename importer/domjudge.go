@@ -0,0 +1,181 @@
+package importer
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"zuccacm-server/db"
+	"zuccacm-server/enum/verdict"
+)
+
+type clicsContest struct {
+	Id        string `json:"id"`
+	Name      string `json:"name"`
+	StartTime string `json:"start_time"`
+	Duration  string `json:"duration"`
+}
+
+type clicsProblem struct {
+	Id      string `json:"id"`
+	Label   string `json:"label"`
+	Ordinal int    `json:"ordinal"`
+}
+
+type clicsSubmission struct {
+	Id        string `json:"id"`
+	TeamId    string `json:"team_id"`
+	ProblemId string `json:"problem_id"`
+	Time      string `json:"time"`
+}
+
+type clicsJudgement struct {
+	SubmissionId    string `json:"submission_id"`
+	JudgementTypeId string `json:"judgement_type_id"`
+}
+
+// parseDOMjudge parse zip of CLICS contest package, files are found by name in any directory
+// contest.json and problems.json are required, submissions.json and judgements.json are optional
+// the account of a submission is the team id
+func parseDOMjudge(data []byte, opt Options) (*contestData, error) {
+	z, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range z.File {
+		files[path.Base(f.Name)] = f
+	}
+	readJSON := func(name string, v interface{}, required bool) error {
+		f, ok := files[name]
+		if !ok {
+			if required {
+				return fmt.Errorf("%s not found in contest package", name)
+			}
+			return nil
+		}
+		r, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		b, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(b, v)
+	}
+
+	var c clicsContest
+	var problems []clicsProblem
+	var submissions []clicsSubmission
+	var judgements []clicsJudgement
+	if err := readJSON("contest.json", &c, true); err != nil {
+		return nil, err
+	}
+	if err := readJSON("problems.json", &problems, true); err != nil {
+		return nil, err
+	}
+	if opt.WithSubmissions {
+		if err := readJSON("submissions.json", &submissions, false); err != nil {
+			return nil, err
+		}
+		if err := readJSON("judgements.json", &judgements, false); err != nil {
+			return nil, err
+		}
+	}
+	if opt.OJ == "" {
+		return nil, errors.New("oj is required by domjudge contest package")
+	}
+
+	ret := &contestData{
+		OJ: opt.OJ,
+		Contest: db.Contest{
+			Cid:      c.Id,
+			Name:     c.Name,
+			Problems: make([]db.Problem, 0),
+		},
+		Submissions: make([]db.Submission, 0),
+	}
+	if c.StartTime != "" {
+		start, err := parseCLICSTime(c.StartTime)
+		if err != nil {
+			return nil, err
+		}
+		ret.Contest.StartTime = db.Datetime(start)
+	}
+	if c.Duration != "" {
+		d, err := parseCLICSDuration(c.Duration)
+		if err != nil {
+			return nil, err
+		}
+		ret.Contest.Duration = int(d.Minutes())
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		return problems[i].Ordinal < problems[j].Ordinal
+	})
+	for _, p := range problems {
+		ret.Contest.Problems = append(ret.Contest.Problems, db.Problem{
+			Pid:   p.Id,
+			Index: p.Label,
+		})
+	}
+
+	// the last judgement of a submission is valid
+	judgementOf := make(map[string]string)
+	for _, j := range judgements {
+		judgementOf[j.SubmissionId] = j.JudgementTypeId
+	}
+	for _, s := range submissions {
+		t, err := parseCLICSTime(s.Time)
+		if err != nil {
+			return nil, err
+		}
+		v, err := verdict.Parse(judgementOf[s.Id])
+		if err != nil {
+			v = verdict.OT
+		}
+		ret.Submissions = append(ret.Submissions, db.Submission{
+			Username:   s.TeamId,
+			Sid:        fmt.Sprintf("%s-%s", c.Id, s.Id),
+			Pid:        s.ProblemId,
+			IsAccepted: v == verdict.OK,
+			Verdict:    v,
+			CreateTime: db.Datetime(t.Local()),
+		})
+	}
+	return ret, nil
+}
+
+// parseCLICSTime parse time like '2021-04-01T10:00:00.000+08' or '2021-04-01T10:00:00+08:00'
+func parseCLICSTime(s string) (t time.Time, err error) {
+	for _, layout := range []string{"2006-01-02T15:04:05Z07:00", "2006-01-02T15:04:05Z07"} {
+		if t, err = time.Parse(layout, s); err == nil {
+			return
+		}
+	}
+	return
+}
+
+// parseCLICSDuration parse duration like '5:00:00.000'
+func parseCLICSDuration(s string) (time.Duration, error) {
+	parts := strings.Split(strings.TrimPrefix(s, "-"), ":")
+	if len(parts) != 3 {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	h, err1 := strconv.Atoi(parts[0])
+	m, err2 := strconv.Atoi(parts[1])
+	sec, err3 := strconv.ParseFloat(parts[2], 64)
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("invalid duration: %s", s)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(sec*float64(time.Second)), nil
+}
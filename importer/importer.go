@@ -0,0 +1,140 @@
+package importer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"time"
+
+	"zuccacm-server/db"
+)
+
+// supported formats of contest data
+const (
+	FormatCodeforces = "codeforces" // response of codeforces api contest.standings
+	FormatAtCoder    = "atcoder"    // json of atcoder standings (/contests/{id}/standings/json)
+	FormatDOMjudge   = "domjudge"   // zip of CLICS contest package (contest.json, problems.json, ...)
+)
+
+// Options of importing, zero value means not specified
+// OJ, Name, StartTime and Duration will override the values in data
+type Options struct {
+	OJ              string
+	Name            string
+	StartTime       time.Time
+	Duration        int
+	Groups          []int
+	Teams           []int
+	WithSubmissions bool
+}
+
+// contestData is what parsed from data
+// Submission.Username is account of the OJ (like what spider submits)
+type contestData struct {
+	OJ          string
+	Contest     db.Contest
+	Submissions []db.Submission
+}
+
+type parser func(data []byte, opt Options) (*contestData, error)
+
+var parsers = map[string]parser{
+	FormatCodeforces: parseCodeforces,
+	FormatAtCoder:    parseAtCoder,
+	FormatDOMjudge:   parseDOMjudge,
+}
+
+// Import parse data and add the contest (with problems)
+// submissions are added only if opt.WithSubmissions=true, and only those of bound accounts are kept
+// return the new contest and the number of added submissions
+func Import(ctx context.Context, format string, data []byte, opt Options) (db.Contest, int64, error) {
+	parse, ok := parsers[format]
+	if !ok {
+		return db.Contest{}, 0, fmt.Errorf("not supported format: %s", format)
+	}
+	d, err := parse(data, opt)
+	if err != nil {
+		return db.Contest{}, 0, err
+	}
+	c := d.Contest
+	if opt.OJ != "" {
+		d.OJ = opt.OJ
+	}
+	if opt.Name != "" {
+		c.Name = opt.Name
+	}
+	if !opt.StartTime.IsZero() {
+		c.StartTime = db.Datetime(opt.StartTime)
+	}
+	if opt.Duration > 0 {
+		c.Duration = opt.Duration
+	}
+	if time.Time(c.StartTime).IsZero() || c.Duration <= 0 {
+		return db.Contest{}, 0, errors.New("start time and duration of contest are required")
+	}
+
+	oj := db.OJMapStoI(db.GetAllOJ(ctx))
+	ojId, ok := oj[d.OJ]
+	if !ok {
+		return db.Contest{}, 0, fmt.Errorf("oj not found: %s", d.OJ)
+	}
+	c.OjId = ojId
+	for i := range c.Problems {
+		c.Problems[i].OjId = ojId
+	}
+	if c.Cid != "" {
+		if id, ok := db.GetContestIdByCid(ctx, ojId, c.Cid); ok {
+			return db.Contest{}, 0, fmt.Errorf("contest %s of %s has been imported as contest %d", c.Cid, d.OJ, id)
+		}
+	}
+	c.Groups = opt.Groups
+	c.Teams = opt.Teams
+	c = db.AddContest(ctx, c)
+
+	var inserted int64
+	if opt.WithSubmissions && len(d.Submissions) > 0 {
+		for i := range d.Submissions {
+			d.Submissions[i].OjId = ojId
+			d.Submissions[i].AccountOjId = ojId
+		}
+		inserted = db.AddSubmission(ctx, d.Submissions)
+	}
+	return c, inserted, nil
+}
+
+// synthesize make submissions from result of standings (which has no detail of submissions)
+// rejected submissions are placed just before the accepted one, or at the end of contest if not accepted
+func synthesize(cid, account, pid string, rejected int, acceptedAt, end time.Time, score *float64) []db.Submission {
+	ret := make([]db.Submission, 0)
+	t := end
+	if !acceptedAt.IsZero() {
+		t = acceptedAt.Add(-time.Second)
+	}
+	for i := 0; i < rejected; i++ {
+		ret = append(ret, db.Submission{
+			Username:   account,
+			Sid:        synthesizeSid(cid, account, pid, i),
+			Pid:        pid,
+			IsAccepted: false,
+			CreateTime: db.Datetime(t),
+		})
+	}
+	if !acceptedAt.IsZero() {
+		ret = append(ret, db.Submission{
+			Username:   account,
+			Sid:        synthesizeSid(cid, account, pid, rejected),
+			Pid:        pid,
+			IsAccepted: true,
+			Score:      score,
+			CreateTime: db.Datetime(acceptedAt),
+		})
+	}
+	return ret
+}
+
+func synthesizeSid(cid, account, pid string, k int) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(fmt.Sprintf("%s/%s/%s/%d", cid, account, pid, k)))
+	return fmt.Sprintf("import-%x", h.Sum64())
+}
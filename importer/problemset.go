@@ -0,0 +1,52 @@
+package importer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"zuccacm-server/db"
+)
+
+type cfProblemset struct {
+	Status  string `json:"status"`
+	Comment string `json:"comment"`
+	Result  struct {
+		Problems []struct {
+			ContestId int      `json:"contestId"`
+			Index     string   `json:"index"`
+			Name      string   `json:"name"`
+			Rating    int      `json:"rating"`
+			Tags      []string `json:"tags"`
+		} `json:"problems"`
+	} `json:"result"`
+}
+
+// ImportProblemset upsert metadata of problems from the response of codeforces api problemset.problems
+// return the number of problems
+func ImportProblemset(ctx context.Context, data []byte) (int, error) {
+	var s cfProblemset
+	if err := json.Unmarshal(data, &s); err != nil {
+		return 0, err
+	}
+	if s.Status != "OK" {
+		return 0, errors.New("codeforces response is not OK: " + s.Comment)
+	}
+	ojId, ok := db.OJMapStoI(db.GetAllOJ(ctx))[FormatCodeforces]
+	if !ok {
+		return 0, fmt.Errorf("oj not found: %s", FormatCodeforces)
+	}
+	problems := make([]db.ProblemMeta, 0)
+	for _, p := range s.Result.Problems {
+		problems = append(problems, db.ProblemMeta{
+			OjId:       ojId,
+			Pid:        fmt.Sprintf("%d%s", p.ContestId, p.Index),
+			Title:      p.Name,
+			Difficulty: p.Rating,
+			Tags:       p.Tags,
+		})
+	}
+	db.UpsertProblems(ctx, problems)
+	return len(problems), nil
+}
@@ -0,0 +1,48 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/config"
+	"zuccacm-server/db"
+)
+
+func init() {
+	AddTask(runner, "0 3 * * *", scanMemberActivity)
+}
+
+// scanMemberActivity add alerts of at-risk members
+// an inactive member is alerted once until active again, and a drop is alerted at most once a week
+func scanMemberActivity() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	cfg := config.Instance.TrainingConfig
+	th := db.NewAlertThreshold(cfg.InactiveDays, cfg.DropRatio, cfg.MinWeeklySolved)
+	now := time.Now()
+	added := 0
+	for _, m := range db.DetectAtRiskMembers(ctx, th, now) {
+		for _, kind := range m.Kinds {
+			var detail string
+			var since time.Time
+			switch kind {
+			case db.AlertInactive:
+				detail = "从未有训练记录"
+				if m.LastActive != nil {
+					detail = fmt.Sprintf("已 %d 天无训练记录", m.InactiveDays)
+					since = time.Time(*m.LastActive)
+				}
+			case db.AlertDrop:
+				detail = fmt.Sprintf("最近一周解题 %d，此前平均每周 %.1f", m.WeekSolved, m.BaselineSolved)
+				since = now.AddDate(0, 0, -7)
+			}
+			if db.AddMemberAlert(ctx, m.Username, kind, detail, since) {
+				added++
+			}
+		}
+	}
+	log.WithField("added", added).Info("member activity scanned")
+}
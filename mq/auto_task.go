@@ -10,8 +10,10 @@ import (
 	"zuccacm-server/db"
 )
 
+// runner recovers panics of tasks (which use must* helpers of db) so that the server keeps running
+var runner = cron.New(cron.WithChain(cron.Recover(cron.PrintfLogger(log.StandardLogger()))))
+
 func init() {
-	runner := cron.New()
 	AddTask(runner, "40 * * * *", refreshSubmission)
 	AddTask(runner, "10 * * * *", refreshRatingCodeforces)
 	AddTask(runner, "20 4 * * *", refreshGroupSubmission)
@@ -27,6 +29,12 @@ func AddTask(taskRunner *cron.Cron, spec string, cmd func()) {
 	}
 }
 
+// Schedule add cmd to the auto task runner
+// it is used by packages which can't be imported by mq (such as handler)
+func Schedule(spec string, cmd func()) {
+	AddTask(runner, spec, cmd)
+}
+
 func refreshSubmission() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
 	defer cancel()
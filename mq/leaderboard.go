@@ -0,0 +1,46 @@
+package mq
+
+import (
+	"context"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/db"
+)
+
+func init() {
+	AddTask(runner, "5 0 * * *", finalizeLeaderboards)
+}
+
+// finalizeLeaderboards persist leaderboards of all periods which ended since the last final one of each kind
+// if there is no final one yet, only the period which ended yesterday is finalized
+func finalizeLeaderboards() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	now := time.Now()
+	for _, kind := range db.LeaderboardKinds {
+		t := now.AddDate(0, 0, -1)
+		if last, ok := db.GetLastFinalLeaderboardBegin(ctx, kind); ok {
+			_, _, end := db.LeaderboardPeriod(kind, last)
+			t = end.Add(time.Second)
+		}
+		for {
+			_, begin, end := db.LeaderboardPeriod(kind, t)
+			if end.After(now) {
+				break
+			}
+			t = end.Add(time.Second)
+			if _, ok := db.GetFinalLeaderboard(ctx, kind, begin); ok {
+				continue
+			}
+			l := db.CalcLeaderboard(ctx, kind, begin)
+			id := db.SaveLeaderboard(ctx, l)
+			log.WithFields(log.Fields{
+				"id":   id,
+				"kind": kind,
+				"name": l.Name,
+			}).Info("leaderboard finalized")
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package mq
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/db"
+	"zuccacm-server/webhook"
+)
+
+// maxScheduledContests limit contests created by a schedule in one run
+const maxScheduledContests = 50
+
+func init() {
+	AddTask(runner, "30 * * * *", createScheduledContests)
+	AddTask(runner, "*/10 * * * *", dispatchScheduledContests)
+}
+
+// createScheduledContests create contests which start in the next LeadDays days for each schedule
+func createScheduledContests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	now := time.Now()
+	for _, s := range db.GetContestSchedules(ctx, true) {
+		spec, err := cron.ParseStandard(s.Spec)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"schedule_id": s.Id,
+				"spec":        s.Spec,
+				"error":       err,
+			}).Error("parse schedule spec failed")
+			continue
+		}
+		last := time.Time(s.LastTime)
+		if last.Before(now) {
+			last = now
+		}
+		end := now.AddDate(0, 0, s.LeadDays)
+		for i := 0; i < maxScheduledContests; i++ {
+			t := spec.Next(last)
+			if t.After(end) {
+				break
+			}
+			c := db.AddScheduledContest(ctx, s.Id, db.Contest{
+				OjId:        s.OjId,
+				Name:        fmt.Sprintf("%s %s", s.Name, db.Datetime(t).Date()),
+				StartTime:   db.Datetime(t),
+				Duration:    s.Duration,
+				ScoringMode: s.ScoringMode,
+				Groups:      []int{s.GroupId},
+				Teams:       s.Teams,
+			})
+			webhook.Emit(webhook.ContestCreated, c)
+			log.WithFields(log.Fields{
+				"schedule_id": s.Id,
+				"contest_id":  c.Id,
+			}).Info("scheduled contest has been created")
+			last = t
+		}
+	}
+}
+
+// dispatchScheduledContests create ContestTask for scheduled contests once cid is set
+func dispatchScheduledContests() {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+	for _, c := range db.GetUndispatchedContests(ctx) {
+		ContestTask(c.OjId, c.Id, c.Cid)
+		db.SetContestDispatched(ctx, c.Id)
+	}
+}
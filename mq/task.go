@@ -16,6 +16,12 @@ func SubmissionTask(username []string, count int, group []string, groupCount int
 	return
 }
 
+// WithId set id of the task, spider posts it back to /submission/task_done when the task is finished
+func (t *Task) WithId(id string) *Task {
+	t.mustSet(id, "task_id")
+	return t
+}
+
 func ContestTask(ojId, id int, cid string) {
 	t := newTask()
 	t.mustSet("contest", "task_type")
@@ -33,3 +39,17 @@ func RatingTask(username []string) (t *Task) {
 	t.mustSet(username, "username")
 	return
 }
+
+// VerifyTask ask spider to check the ownership challenge of account
+func VerifyTask(account, method, token, pid string, since db.Datetime) (t *Task) {
+	t = newTask()
+	t.mustSet("verify", "task_type")
+	t.mustSet(account, "account")
+	t.mustSet(method, "method")
+	t.mustSet(token, "token")
+	if pid != "" {
+		t.mustSet(pid, "pid")
+	}
+	t.mustSet(since, "since")
+	return
+}
@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// emailTimeout bound the whole SMTP session if ctx has no deadline
+const emailTimeout = time.Minute
+
+type email struct {
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+// NewEmail return a channel sending emails by SMTP, no auth if user is empty
+func NewEmail(host string, port int, user, pwd, from string) Channel {
+	e := &email{
+		addr: fmt.Sprintf("%s:%d", host, port),
+		from: from,
+	}
+	if user != "" {
+		e.auth = smtp.PlainAuth("", user, pwd, host)
+	}
+	if e.from == "" {
+		e.from = user
+	}
+	return e
+}
+
+func (e *email) Name() string {
+	return "email"
+}
+
+// Send send one email to all recipients in bcc
+func (e *email) Send(ctx context.Context, m Message) error {
+	if len(m.Emails) == 0 {
+		return nil
+	}
+	var sb strings.Builder
+	sb.WriteString("From: " + e.from + "\r\n")
+	sb.WriteString("To: undisclosed-recipients:;\r\n")
+	sb.WriteString("Subject: " + mime.BEncoding.Encode("UTF-8", m.Title) + "\r\n")
+	sb.WriteString("MIME-Version: 1.0\r\n")
+	sb.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+	sb.WriteString("\r\n")
+	sb.WriteString(strings.ReplaceAll(m.Content, "\n", "\r\n"))
+	sb.WriteString("\r\n")
+	return e.sendMail(ctx, m.Emails, []byte(sb.String()))
+}
+
+// sendMail is smtp.SendMail bounded by ctx
+func (e *email) sendMail(ctx context.Context, to []string, msg []byte) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, emailTimeout)
+		defer cancel()
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", e.addr)
+	if err != nil {
+		return err
+	}
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		conn.Close()
+		return err
+	}
+	host, _, _ := net.SplitHostPort(e.addr)
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err = c.StartTLS(&tls.Config{ServerName: host}); err != nil {
+			return err
+		}
+	}
+	if e.auth != nil {
+		if ok, _ := c.Extension("AUTH"); ok {
+			if err = c.Auth(e.auth); err != nil {
+				return err
+			}
+		}
+	}
+	if err = c.Mail(e.from); err != nil {
+		return err
+	}
+	for _, addr := range to {
+		if err = c.Rcpt(addr); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(msg); err != nil {
+		return err
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
@@ -0,0 +1,164 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"mime"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// smtpMail is a mail received by smtpServer
+type smtpMail struct {
+	from string
+	to   []string
+	data string
+}
+
+// smtpServer start a minimal SMTP server accepting one session, the mail is sent to the returned channel
+func smtpServer(t *testing.T) (host string, port int, mails <-chan smtpMail) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	ch := make(chan smtpMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		reply := func(s string) {
+			conn.Write([]byte(s + "\r\n"))
+		}
+		var m smtpMail
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			cmd := strings.ToUpper(line)
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				m.from = strings.Trim(line[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				m.to = append(m.to, strings.Trim(line[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 end with <CRLF>.<CRLF>")
+				var sb strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					sb.WriteString(l)
+				}
+				m.data = sb.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 bye")
+				ch <- m
+				return
+			default:
+				reply("502 not implemented")
+			}
+		}
+	}()
+	addr := ln.Addr().(*net.TCPAddr)
+	return addr.IP.String(), addr.Port, ch
+}
+
+func TestEmailSend(t *testing.T) {
+	host, port, mails := smtpServer(t)
+	e := NewEmail(host, port, "", "", "acm@example.com")
+	m := Message{
+		Kind:    "reminder",
+		Title:   "比赛提醒：周赛",
+		Content: "line1\nline2",
+		Emails:  []string{"a@example.com", "b@example.com"},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := e.Send(ctx, m); err != nil {
+		t.Fatal(err)
+	}
+	var got smtpMail
+	select {
+	case got = <-mails:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+	if got.from != "acm@example.com" {
+		t.Errorf("from = %q", got.from)
+	}
+	if strings.Join(got.to, ",") != "a@example.com,b@example.com" {
+		t.Errorf("to = %v", got.to)
+	}
+	i := strings.Index(got.data, "\r\n\r\n")
+	if i < 0 {
+		t.Fatalf("no header in %q", got.data)
+	}
+	header, body := got.data[:i], got.data[i+4:]
+	var subject string
+	for _, l := range strings.Split(header, "\r\n") {
+		if strings.HasPrefix(l, "Subject: ") {
+			subject, _ = new(mime.WordDecoder).DecodeHeader(l[len("Subject: "):])
+		}
+	}
+	if subject != m.Title {
+		t.Errorf("subject = %q, want %q", subject, m.Title)
+	}
+	if strings.Contains(header, "a@example.com") {
+		t.Errorf("recipients leaked in header: %q", header)
+	}
+	if body != "line1\r\nline2\r\n" {
+		t.Errorf("body = %q", body)
+	}
+}
+
+func TestEmailSendNoRecipient(t *testing.T) {
+	e := NewEmail("127.0.0.1", 1, "", "", "acm@example.com")
+	if err := e.Send(context.Background(), Message{Title: "x"}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEmailSendTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	// accept but never greet
+	go func() {
+		conn, err := ln.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(2 * time.Second)
+		}
+	}()
+	e := NewEmail("127.0.0.1", ln.Addr().(*net.TCPAddr).Port, "", "", "acm@example.com")
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := e.Send(ctx, Message{Emails: []string{"a@example.com"}}); err == nil {
+		t.Fatal("want timeout error")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Errorf("send took %v, want bounded by ctx", d)
+	}
+}
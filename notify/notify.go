@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// Message is what to notify, each channel decides how to deliver it
+// Emails and Usernames are the recipients, chat channels send to the whole group
+type Message struct {
+	Kind      string   `json:"kind"`
+	ContestId int      `json:"contest_id"`
+	Title     string   `json:"title"`
+	Content   string   `json:"content"`
+	Usernames []string `json:"usernames"`
+	Emails    []string `json:"-"`
+}
+
+func (m Message) text() string {
+	return fmt.Sprintf("%s\n\n%s", m.Title, m.Content)
+}
+
+type Channel interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+var channels []Channel
+
+// Register add a channel, messages will be sent by all registered channels
+func Register(c Channel) {
+	channels = append(channels, c)
+}
+
+// Send deliver m by all channels, failure of one channel doesn't stop others
+// return the first error
+func Send(ctx context.Context, m Message) (err error) {
+	for _, c := range channels {
+		if e := c.Send(ctx, m); e != nil {
+			log.WithFields(log.Fields{
+				"channel":    c.Name(),
+				"kind":       m.Kind,
+				"contest_id": m.ContestId,
+				"error":      e,
+			}).Error("send notification failed")
+			if err == nil {
+				err = fmt.Errorf("%s: %w", c.Name(), e)
+			}
+		}
+	}
+	return
+}
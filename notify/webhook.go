@@ -0,0 +1,91 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// supported chat bots
+const (
+	BotDingTalk = "dingtalk" // dingtalk group robot
+	BotCQHTTP   = "cqhttp"   // send_group_msg api of QQ bot (go-cqhttp)
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+func postJSON(ctx context.Context, url string, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return nil
+}
+
+type webhook struct {
+	url string
+}
+
+// NewWebhook return a channel posting Message in json to url
+func NewWebhook(url string) Channel {
+	return &webhook{url}
+}
+
+func (h *webhook) Name() string {
+	return "webhook"
+}
+
+func (h *webhook) Send(ctx context.Context, m Message) error {
+	return postJSON(ctx, h.url, m)
+}
+
+type bot struct {
+	typ     string
+	url     string
+	groupId int64
+}
+
+// NewBot return a channel sending text to a chat group, typ is BotDingTalk (default) or BotCQHTTP
+// groupId is only used by BotCQHTTP
+func NewBot(typ, url string, groupId int64) Channel {
+	if typ == "" {
+		typ = BotDingTalk
+	}
+	return &bot{typ, url, groupId}
+}
+
+func (b *bot) Name() string {
+	return "bot-" + b.typ
+}
+
+func (b *bot) Send(ctx context.Context, m Message) error {
+	switch b.typ {
+	case BotDingTalk:
+		return postJSON(ctx, b.url, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]string{"content": m.text()},
+		})
+	case BotCQHTTP:
+		return postJSON(ctx, b.url, map[string]interface{}{
+			"group_id": b.groupId,
+			"message":  m.text(),
+		})
+	}
+	return fmt.Errorf("unknown bot type: %s", b.typ)
+}
@@ -0,0 +1,102 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+// request is a request received by recorder
+type request struct {
+	method      string
+	contentType string
+	body        []byte
+}
+
+// recorder start a server replying status, received requests are sent to the returned channel
+func recorder(t *testing.T, status int) (*httptest.Server, <-chan request) {
+	t.Helper()
+	ch := make(chan request, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		ch <- request{r.Method, r.Header.Get("Content-Type"), b}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, ch
+}
+
+func TestWebhookSend(t *testing.T) {
+	srv, reqs := recorder(t, http.StatusOK)
+	m := Message{
+		Kind:      "result",
+		ContestId: 7,
+		Title:     "比赛结果：周赛",
+		Content:   "1. team  3 题",
+		Usernames: []string{"alice", "bob"},
+		Emails:    []string{"alice@example.com"},
+	}
+	if err := NewWebhook(srv.URL).Send(context.Background(), m); err != nil {
+		t.Fatal(err)
+	}
+	r := <-reqs
+	if r.method != http.MethodPost || r.contentType != "application/json" {
+		t.Errorf("method = %s, content type = %s", r.method, r.contentType)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(r.body, &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"kind":       "result",
+		"contest_id": float64(7),
+		"title":      m.Title,
+		"content":    m.Content,
+		"usernames":  []interface{}{"alice", "bob"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %v, want %v", got, want)
+	}
+}
+
+func TestWebhookSendStatus(t *testing.T) {
+	srv, reqs := recorder(t, http.StatusInternalServerError)
+	if err := NewWebhook(srv.URL).Send(context.Background(), Message{Title: "x"}); err == nil {
+		t.Error("want error on non-2xx status")
+	}
+	<-reqs
+}
+
+func TestBotSend(t *testing.T) {
+	m := Message{Title: "比赛提醒：周赛", Content: "19:00 开始"}
+	tests := []struct {
+		typ  string
+		want map[string]interface{}
+	}{
+		{BotDingTalk, map[string]interface{}{
+			"msgtype": "text",
+			"text":    map[string]interface{}{"content": m.text()},
+		}},
+		{BotCQHTTP, map[string]interface{}{
+			"group_id": float64(12345),
+			"message":  m.text(),
+		}},
+	}
+	for _, tt := range tests {
+		srv, reqs := recorder(t, http.StatusOK)
+		if err := NewBot(tt.typ, srv.URL, 12345).Send(context.Background(), m); err != nil {
+			t.Fatalf("%s: %v", tt.typ, err)
+		}
+		var got map[string]interface{}
+		if err := json.Unmarshal((<-reqs).body, &got); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: payload = %v, want %v", tt.typ, got, tt.want)
+		}
+	}
+}
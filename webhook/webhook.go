@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"zuccacm-server/db"
+)
+
+// types of domain events
+const (
+	SubmissionAccepted = "submission.accepted"
+	ContestCreated     = "contest.created"
+	RatingUpdated      = "rating.updated"
+	AwardAdded         = "award.added"
+)
+
+const (
+	maxAttempts = 5
+	// delay before the k-th retry is backoff * 2^(k-1)
+	backoff = 2 * time.Second
+	// error saved in delivery log is truncated to it (in runes)
+	maxErrorLength = 500
+)
+
+var client = &http.Client{Timeout: 10 * time.Second}
+
+type payload struct {
+	Id        string      `json:"id"`
+	Event     string      `json:"event"`
+	Timestamp int64       `json:"timestamp"`
+	Data      interface{} `json:"data"`
+}
+
+// Sign return hex HMAC-SHA256 of body, receivers should check header X-Zuccacm-Signature: sha256=<Sign>
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func newDeliveryId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+const (
+	// number of goroutines delivering webhooks
+	workers = 4
+	// attempts waiting for workers, an attempt is dropped when it is full
+	queueSize = 1024
+	// deliveries of a webhook in progress (including those waiting for retry), new ones are dropped beyond it
+	maxPendingPerHook = 64
+)
+
+// job is an attempt of a delivery
+type job struct {
+	h       db.Webhook
+	p       payload
+	body    []byte
+	attempt int
+}
+
+var (
+	queue     = make(chan job, queueSize)
+	startOnce sync.Once
+	// pending is the number of deliveries in progress of each webhook
+	pending   = make(map[int]int)
+	pendingMu sync.Mutex
+)
+
+// Emit deliver the event to subscribed webhooks in background
+// it never blocks or panics the caller
+func Emit(event string, data interface{}) {
+	EmitAll(event, []interface{}{data})
+}
+
+// EmitAll is Emit for a batch of events of the same type, webhooks are loaded once
+func EmitAll(event string, data []interface{}) {
+	if len(data) == 0 {
+		return
+	}
+	startOnce.Do(func() {
+		for i := 0; i < workers; i++ {
+			go worker()
+		}
+	})
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				log.WithFields(log.Fields{
+					"event": event,
+					"error": err,
+				}).Error("emit webhook event failed")
+			}
+		}()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		hooks := db.GetWebhooks(ctx, true)
+		cancel()
+		matched := make([]db.Webhook, 0)
+		for _, h := range hooks {
+			if h.Match(event) {
+				matched = append(matched, h)
+			}
+		}
+		if len(matched) == 0 {
+			return
+		}
+		for _, x := range data {
+			p := payload{
+				Id:        newDeliveryId(),
+				Event:     event,
+				Timestamp: time.Now().Unix(),
+				Data:      x,
+			}
+			body, err := json.Marshal(p)
+			if err != nil {
+				panic(err)
+			}
+			for _, h := range matched {
+				start(job{h, p, body, 1})
+			}
+		}
+	}()
+}
+
+// start a delivery unless the webhook has too many deliveries in progress
+func start(j job) {
+	pendingMu.Lock()
+	full := pending[j.h.Id] >= maxPendingPerHook
+	if !full {
+		pending[j.h.Id]++
+	}
+	pendingMu.Unlock()
+	if full {
+		log.WithFields(log.Fields{
+			"webhook_id":  j.h.Id,
+			"delivery_id": j.p.Id,
+		}).Warn("too many pending deliveries of webhook, drop it")
+		return
+	}
+	enqueue(j)
+}
+
+// finish a delivery after it succeeds or runs out of attempts
+func finish(j job) {
+	pendingMu.Lock()
+	if pending[j.h.Id]--; pending[j.h.Id] <= 0 {
+		delete(pending, j.h.Id)
+	}
+	pendingMu.Unlock()
+}
+
+// enqueue never blocks, the delivery is given up if the queue is full
+func enqueue(j job) {
+	select {
+	case queue <- j:
+	default:
+		log.WithFields(log.Fields{
+			"webhook_id":  j.h.Id,
+			"delivery_id": j.p.Id,
+			"attempt":     j.attempt,
+		}).Warn("webhook queue is full, drop delivery")
+		finish(j)
+	}
+}
+
+func worker() {
+	for j := range queue {
+		deliver(j)
+	}
+}
+
+// truncate return the first n runes of s
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
+}
+
+// deliver make an attempt to post body to the webhook, which is saved in delivery log
+// failed attempts are retried with exponential backoff until maxAttempts, the worker doesn't wait for it
+func deliver(j job) {
+	done := true
+	defer func() {
+		if err := recover(); err != nil {
+			log.WithFields(log.Fields{
+				"webhook_id":  j.h.Id,
+				"delivery_id": j.p.Id,
+				"error":       err,
+			}).Error("deliver webhook panic")
+		}
+		if done {
+			finish(j)
+		}
+	}()
+	code, err := post(j.h, j.p, j.body)
+	d := db.WebhookDelivery{
+		WebhookId:  j.h.Id,
+		DeliveryId: j.p.Id,
+		Event:      j.p.Event,
+		Payload:    string(j.body),
+		Attempt:    j.attempt,
+		StatusCode: code,
+		IsSuccess:  err == nil,
+		CreateTime: db.Datetime(time.Now()),
+	}
+	if err != nil {
+		d.Error = truncate(err.Error(), maxErrorLength)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	db.AddWebhookDelivery(ctx, d)
+	cancel()
+	if err == nil {
+		return
+	}
+	log.WithFields(log.Fields{
+		"webhook_id":  j.h.Id,
+		"delivery_id": j.p.Id,
+		"attempt":     j.attempt,
+		"error":       err,
+	}).Warn("deliver webhook failed")
+	if j.attempt < maxAttempts {
+		done = false
+		next := j
+		next.attempt++
+		time.AfterFunc(backoff<<(j.attempt-1), func() {
+			enqueue(next)
+		})
+	}
+}
+
+func post(h db.Webhook, p payload, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Zuccacm-Event", p.Event)
+	req.Header.Set("X-Zuccacm-Delivery", p.Id)
+	req.Header.Set("X-Zuccacm-Signature", "sha256="+Sign(h.Secret, body))
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode/100 != 2 {
+		return resp.StatusCode, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}